@@ -32,6 +32,7 @@ import (
 //		BenchmarkReflectNilCheckCorrectly_Nonptr-8      30000000                49.1 ns/op            48 B/op          1 allocs/op
 //
 // Conclusion: this is basically a no-go if we want this library to be near-zero cost.
+// (So it's opt-in: build with `-tags errcat_debug` to get the checks; see `errcat.TypedNilReport`.)
 //
 
 // Related learning: changing all of the interface methods to only work on the
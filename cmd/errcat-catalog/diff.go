@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/warpfork/go-errcat"
+)
+
+/*
+	The "diff" subcommand: compare two catalogs and report changes,
+	exiting 1 if any of them are breaking.
+*/
+func runDiff(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("errcat-catalog diff", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 2 {
+		fmt.Fprintln(stderr, "usage: errcat-catalog diff <old> <new>")
+		return 2
+	}
+	from, err := loadCatalog(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "errcat-catalog: %s\n", err)
+		return 2
+	}
+	to, err := loadCatalog(flags.Arg(1))
+	if err != nil {
+		fmt.Fprintf(stderr, "errcat-catalog: %s\n", err)
+		return 2
+	}
+
+	d := errcat.DiffCatalogs(from, to)
+	for _, e := range d.Removed {
+		fmt.Fprintf(stdout, "removed: %q (%s)\n", e.Category, describeEntry(e))
+	}
+	for _, c := range d.Renamed {
+		fmt.Fprintf(stdout, "renamed: %q -> %q (%s)\n", c.Old.Category, c.New.Category, describeEntry(c.New))
+	}
+	for _, c := range d.Retyped {
+		fmt.Fprintf(stdout, "retyped: %q: %s -> %s\n", c.Old.Category, describeEntry(c.Old), describeEntry(c.New))
+	}
+	for _, e := range d.Added {
+		fmt.Fprintf(stdout, "added: %q (%s)\n", e.Category, describeEntry(e))
+	}
+	if d.Breaking() {
+		fmt.Fprintln(stderr, "errcat-catalog: breaking category changes")
+		return 1
+	}
+	return 0
+}
+
+/*
+	Load a catalog from a json file, or by extracting it from a source tree
+	if the path is a directory.
+*/
+func loadCatalog(p string) (*errcat.Catalog, error) {
+	fi, err := os.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		return extractCatalog(p)
+	}
+	bs, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	cat := &errcat.Catalog{}
+	if err := json.Unmarshal(bs, cat); err != nil {
+		return nil, fmt.Errorf("%s: %s", p, err)
+	}
+	return cat, nil
+}
+
+func describeEntry(e errcat.CatalogEntry) string {
+	if e.Name == "" {
+		return e.Type
+	}
+	return e.Type + " " + e.Name
+}
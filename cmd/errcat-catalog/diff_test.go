@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunDiff(t *testing.T) {
+	before := t.TempDir()
+	writeTree(t, before, fixtureModule)
+	var catalogJSON bytes.Buffer
+	if code := run([]string{before}, &catalogJSON, os.Stderr); code != 0 {
+		t.Fatalf("exit %d", code)
+	}
+	catalogFile := filepath.Join(t.TempDir(), "catalog.json")
+	if err := os.WriteFile(catalogFile, catalogJSON.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("unchanged", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		if code := run([]string{"diff", catalogFile, before}, &stdout, &stderr); code != 0 {
+			t.Errorf("expected exit 0, got %d: %s", code, stderr.String())
+		}
+		if stdout.Len() != 0 {
+			t.Errorf("expected no output, got:\n%s", stdout.String())
+		}
+	})
+	t.Run("additions only", func(t *testing.T) {
+		after := t.TempDir()
+		writeTree(t, after, fixtureModule)
+		writeTree(t, after, map[string]string{"store/more.go": `package store
+
+const ErrFull = ErrorCategory("store-full")
+`})
+		var stdout, stderr bytes.Buffer
+		if code := run([]string{"diff", catalogFile, after}, &stdout, &stderr); code != 0 {
+			t.Errorf("expected exit 0, got %d: %s", code, stderr.String())
+		}
+		if stdout.String() != "added: \"store-full\" (example.com/svc/store.ErrorCategory ErrFull)\n" {
+			t.Errorf("unexpected output:\n%s", stdout.String())
+		}
+	})
+	t.Run("breaking changes", func(t *testing.T) {
+		after := t.TempDir()
+		writeTree(t, after, fixtureModule)
+		writeTree(t, after, map[string]string{"store/store.go": `package store
+
+import "github.com/warpfork/go-errcat"
+
+type ErrorCategory string
+
+const ErrNotFound = ErrorCategory("store-missing")
+
+func Get() error {
+	return errcat.Errorf(ErrNotFound, "nope")
+}
+`})
+		var stdout, stderr bytes.Buffer
+		if code := run([]string{"diff", catalogFile, after}, &stdout, &stderr); code != 1 {
+			t.Errorf("expected exit 1, got %d: %s", code, stderr.String())
+		}
+		expect := "removed: \"store-corrupt\" (example.com/svc/store.ErrorCategory ErrCorrupt)\n" +
+			"renamed: \"store-not-found\" -> \"store-missing\" (example.com/svc/store.ErrorCategory ErrNotFound)\n"
+		if stdout.String() != expect {
+			t.Errorf("unexpected output:\n%s", stdout.String())
+		}
+	})
+	t.Run("usage", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		if code := run([]string{"diff", catalogFile}, &stdout, &stderr); code != 2 {
+			t.Errorf("expected exit 2, got %d", code)
+		}
+	})
+}
@@ -0,0 +1,397 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/constant"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/warpfork/go-errcat"
+)
+
+/*
+	categoryArgs lists the errcat functions which take a category,
+	and the index of the category among their arguments.
+	Any type whose values are passed there is a category type.
+*/
+var categoryArgs = map[string]int{
+	"Errorf":                         0,
+	"ErrorDetailed":                  0,
+	"Recategorize":                   0,
+	"NewErrorBase":                   0,
+	"New":                            0,
+	"RequireErrorHasCategory":        1,
+	"RequireErrorHasCategoryOrPanic": 1,
+}
+
+// Is this the import path of (some copy of) errcat?
+func isErrcatPath(p string) bool {
+	return p == "github.com/warpfork/go-errcat" || strings.HasSuffix(p, "/go-errcat")
+}
+
+/*
+	extractor loads every package in a module from source and type-checks it.
+
+	Nothing outside the module is loaded: imports of other packages fail
+	(and the type errors that causes are ignored), except errcat, which gets
+	an empty stand-in so calls into it can still be recognized by name.
+	This keeps extraction working offline, with nothing but the source tree.
+*/
+type extractor struct {
+	fset     *token.FileSet
+	root     string                    // Directory of the module root.
+	module   string                    // Module path.
+	dirs     map[string]string         // Import path -> directory, for every package dir in the module.
+	pkgs     map[string]*types.Package // Import path -> checked package.
+	files    map[string][]*ast.File    // Import path -> parsed files.
+	info     *types.Info
+	checking map[string]bool
+}
+
+/*
+	Extract a catalog of every category const declared in the module at or
+	above dir.
+*/
+func extractCatalog(dir string) (*errcat.Catalog, error) {
+	root, module, err := findModule(dir)
+	if err != nil {
+		return nil, err
+	}
+	x := &extractor{
+		fset:     token.NewFileSet(),
+		root:     root,
+		module:   module,
+		dirs:     map[string]string{},
+		pkgs:     map[string]*types.Package{},
+		files:    map[string][]*ast.File{},
+		checking: map[string]bool{},
+		info: &types.Info{
+			Types: map[ast.Expr]types.TypeAndValue{},
+			Defs:  map[*ast.Ident]types.Object{},
+			Uses:  map[*ast.Ident]types.Object{},
+		},
+	}
+	err = filepath.Walk(root, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() {
+			return nil
+		}
+		name := fi.Name()
+		if p != root && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+			return filepath.SkipDir
+		}
+		if p != root {
+			if _, err := os.Stat(filepath.Join(p, "go.mod")); err == nil {
+				return filepath.SkipDir // a nested module isn't ours.
+			}
+		}
+		rel, _ := filepath.Rel(root, p)
+		x.dirs[path.Join(module, filepath.ToSlash(rel))] = p
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(x.dirs))
+	for p := range x.dirs {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		if _, err := x.Import(p); err != nil {
+			return nil, err
+		}
+	}
+	return x.catalog(paths), nil
+}
+
+// Walk up from dir to the nearest go.mod, and return its directory and module path.
+func findModule(dir string) (string, string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", "", err
+	}
+	for d := dir; ; d = filepath.Dir(d) {
+		f, err := os.Open(filepath.Join(d, "go.mod"))
+		if err == nil {
+			defer f.Close()
+			sc := bufio.NewScanner(f)
+			for sc.Scan() {
+				fields := strings.Fields(sc.Text())
+				if len(fields) >= 2 && fields[0] == "module" {
+					return d, strings.Trim(fields[1], `"`), nil
+				}
+			}
+			return "", "", fmt.Errorf("%s: no module line", filepath.Join(d, "go.mod"))
+		}
+		if filepath.Dir(d) == d {
+			return "", "", fmt.Errorf("%s: not in a go module", dir)
+		}
+	}
+}
+
+// Import implements types.Importer, loading packages of the module from source.
+func (x *extractor) Import(p string) (*types.Package, error) {
+	if pkg, ok := x.pkgs[p]; ok {
+		return pkg, nil
+	}
+	dir, ok := x.dirs[p]
+	if !ok {
+		if isErrcatPath(p) {
+			pkg := types.NewPackage(p, "errcat")
+			pkg.MarkComplete()
+			x.pkgs[p] = pkg
+			return pkg, nil
+		}
+		return nil, fmt.Errorf("not loading %q from outside the module", p)
+	}
+	if x.checking[p] {
+		return nil, fmt.Errorf("import cycle through %q", p)
+	}
+	x.checking[p] = true
+	defer delete(x.checking, p)
+
+	pkgs, err := parser.ParseDir(x.fset, dir, func(fi os.FileInfo) bool {
+		if strings.HasSuffix(fi.Name(), "_test.go") {
+			return false
+		}
+		ok, err := build.Default.MatchFile(dir, fi.Name())
+		return err == nil && ok
+	}, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	var files []*ast.File
+	for _, pkg := range pkgs {
+		for _, f := range pkg.Files {
+			files = append(files, f)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return x.fset.File(files[i].Pos()).Name() < x.fset.File(files[j].Pos()).Name() })
+	x.files[p] = files
+	conf := types.Config{Importer: x, Error: func(error) {}}
+	pkg, _ := conf.Check(p, x.fset, files, x.info)
+	x.pkgs[p] = pkg
+	return pkg, nil
+}
+
+/*
+	Assemble the catalog: find the category types (by the calls into errcat
+	they're used in), then every const of those types.
+
+	Categories are qualified by their type's namespace, as `Catalog.Add`
+	would at runtime, where it can be worked out from the source: a
+	`CategoryNamespace` method which returns a constant, or a call to
+	`RegisterNamespace` with a constant or `PackageNamespace` namespace.
+	(The method wins, as it does at runtime.)  Namespaces computed any other
+	way can't be seen here, and the categories come out unqualified.
+*/
+func (x *extractor) catalog(paths []string) *errcat.Catalog {
+	categoryTypes := map[*types.TypeName]bool{}
+	registered := map[*types.TypeName]string{} // Namespaces from RegisterNamespace calls.
+	for _, p := range paths {
+		for _, f := range x.files[p] {
+			names := errcatImportNames(f, x.pkgs[p])
+			ast.Inspect(f, func(n ast.Node) bool {
+				call, ok := n.(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+				var fn string
+				switch fun := call.Fun.(type) {
+				case *ast.SelectorExpr:
+					if id, ok := fun.X.(*ast.Ident); ok && names[id.Name] {
+						fn = fun.Sel.Name
+					}
+				case *ast.Ident:
+					if names[""] {
+						fn = fun.Name
+					}
+				}
+				if fn == "RegisterNamespace" && len(call.Args) == 2 {
+					if tn := x.namedStringType(call.Args[0]); tn != nil {
+						if ns, ok := x.namespaceArg(call.Args[1], names); ok {
+							registered[tn] = ns
+						}
+					}
+				}
+				i, ok := categoryArgs[fn]
+				if !ok || i >= len(call.Args) {
+					return true
+				}
+				if tn := x.namedStringType(call.Args[i]); tn != nil {
+					categoryTypes[tn] = true
+				}
+				return true
+			})
+		}
+	}
+
+	cat := &errcat.Catalog{}
+	for _, p := range paths {
+		for _, f := range x.files[p] {
+			for _, decl := range f.Decls {
+				gd, ok := decl.(*ast.GenDecl)
+				if !ok || gd.Tok != token.CONST {
+					continue
+				}
+				for _, spec := range gd.Specs {
+					vs := spec.(*ast.ValueSpec)
+					doc := vs.Doc
+					if doc == nil && len(gd.Specs) == 1 {
+						doc = gd.Doc
+					}
+					if doc == nil {
+						doc = vs.Comment
+					}
+					for _, name := range vs.Names {
+						obj, ok := x.info.Defs[name].(*types.Const)
+						if !ok || obj.Parent() != obj.Pkg().Scope() || obj.Val().Kind() != constant.String {
+							continue
+						}
+						named, ok := obj.Type().(*types.Named)
+						if !ok || !categoryTypes[named.Obj()] {
+							continue
+						}
+						category := constant.StringVal(obj.Val())
+						ns, ok := x.methodNamespace(named.Obj())
+						if !ok {
+							ns = registered[named.Obj()]
+						}
+						if ns != "" {
+							category = ns + errcat.NamespaceSeparator + category
+						}
+						cat.AddEntry(errcat.CatalogEntry{
+							Category:    category,
+							Name:        obj.Name(),
+							Type:        named.Obj().Pkg().Path() + "." + named.Obj().Name(),
+							Description: strings.TrimSpace(doc.Text()),
+						})
+					}
+				}
+			}
+		}
+	}
+	return cat
+}
+
+/*
+	Return the namespace given as the second argument of a `RegisterNamespace`
+	call, if it's a string constant or a `PackageNamespace` call.
+*/
+func (x *extractor) namespaceArg(e ast.Expr, errcatNames map[string]bool) (string, bool) {
+	if tv, ok := x.info.Types[e]; ok && tv.Value != nil && tv.Value.Kind() == constant.String {
+		return constant.StringVal(tv.Value), true
+	}
+	call, ok := e.(*ast.CallExpr)
+	if !ok || len(call.Args) != 1 {
+		return "", false
+	}
+	switch fun := call.Fun.(type) {
+	case *ast.SelectorExpr:
+		if id, ok := fun.X.(*ast.Ident); !ok || !errcatNames[id.Name] || fun.Sel.Name != "PackageNamespace" {
+			return "", false
+		}
+	case *ast.Ident:
+		if !errcatNames[""] || fun.Name != "PackageNamespace" {
+			return "", false
+		}
+	default:
+		return "", false
+	}
+	if tn := x.namedStringType(call.Args[0]); tn != nil {
+		return tn.Pkg().Path(), true
+	}
+	return "", false
+}
+
+/*
+	Return the namespace given by a type's `CategoryNamespace` method, if it
+	has one whose body just returns a string constant.
+*/
+func (x *extractor) methodNamespace(tn *types.TypeName) (string, bool) {
+	obj, _, _ := types.LookupFieldOrMethod(tn.Type(), true, tn.Pkg(), "CategoryNamespace")
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		return "", false
+	}
+	for _, f := range x.files[tn.Pkg().Path()] {
+		for _, decl := range f.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if !ok || x.info.Defs[fd.Name] != fn || fd.Body == nil || len(fd.Body.List) != 1 {
+				continue
+			}
+			ret, ok := fd.Body.List[0].(*ast.ReturnStmt)
+			if !ok || len(ret.Results) != 1 {
+				return "", false
+			}
+			if tv, ok := x.info.Types[ret.Results[0]]; ok && tv.Value != nil && tv.Value.Kind() == constant.String {
+				return constant.StringVal(tv.Value), true
+			}
+		}
+	}
+	return "", false
+}
+
+/*
+	Return the local names errcat is imported under in a file.
+	The empty name is included if the file is in errcat itself.
+*/
+func errcatImportNames(f *ast.File, pkg *types.Package) map[string]bool {
+	names := map[string]bool{}
+	if pkg != nil && isErrcatPath(pkg.Path()) {
+		names[""] = true
+	}
+	for _, imp := range f.Imports {
+		p := strings.Trim(imp.Path.Value, `"`)
+		if !isErrcatPath(p) {
+			continue
+		}
+		if imp.Name != nil {
+			names[imp.Name.Name] = true
+		} else {
+			names["errcat"] = true
+		}
+	}
+	return names
+}
+
+// Return the defined type of an expression, if it's a string type declared in the module.
+func (x *extractor) namedStringType(e ast.Expr) *types.TypeName {
+	var t types.Type
+	switch e2 := e.(type) {
+	case *ast.Ident:
+		if obj := x.info.Uses[e2]; obj != nil {
+			t = obj.Type()
+		}
+	case *ast.SelectorExpr:
+		if obj := x.info.Uses[e2.Sel]; obj != nil {
+			t = obj.Type()
+		}
+	}
+	if t == nil {
+		t = x.info.TypeOf(e)
+	}
+	named, ok := t.(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return nil
+	}
+	if _, inModule := x.dirs[named.Obj().Pkg().Path()]; !inModule {
+		return nil
+	}
+	if b, ok := named.Underlying().(*types.Basic); !ok || b.Info()&types.IsString == 0 {
+		return nil
+	}
+	return named.Obj()
+}
@@ -0,0 +1,90 @@
+/*
+	errcat-catalog scans the Go source of a module for errcat category consts,
+	and prints a catalog of them.
+
+	Usage:
+
+		errcat-catalog [-format json|markdown] [dir]
+		errcat-catalog diff <old> <new>
+
+	A category type is any string type whose values are passed as the
+	category to errcat.Errorf, ErrorDetailed, Recategorize, or
+	RequireErrorHasCategory anywhere in the module; every package-level
+	const of such a type is cataloged, with its doc comment as the
+	description.
+
+	The module is read from source alone (no network, no build), so it's
+	cheap to run in CI.  The json form is the same as errcat.Catalog's,
+	so it can be checked in and compared against later.
+
+	The diff subcommand does that comparison.  Each of old and new may be a
+	catalog json file, or a directory to extract a catalog from (so two
+	checkouts of a repo, e.g. made with `git worktree`, can be compared
+	directly).  It lists categories which were removed, renamed (the same
+	const now has a different value), retyped (the same value is now
+	declared with a different type), or added, and exits 1 if there were
+	any breaking changes -- that is, anything but additions -- so it can
+	gate releases.  Other failures exit 2.
+*/
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) > 0 && args[0] == "diff" {
+		return runDiff(args[1:], stdout, stderr)
+	}
+	flags := flag.NewFlagSet("errcat-catalog", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	format := flags.String("format", "json", "output format: json or markdown")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	dir := "."
+	switch flags.NArg() {
+	case 0:
+	case 1:
+		dir = flags.Arg(0)
+	default:
+		fmt.Fprintln(stderr, "usage: errcat-catalog [-format json|markdown] [dir]\n       errcat-catalog diff <old> <new>")
+		return 2
+	}
+
+	cat, err := extractCatalog(dir)
+	if err != nil {
+		fmt.Fprintf(stderr, "errcat-catalog: %s\n", err)
+		return 1
+	}
+	switch *format {
+	case "json":
+		bs, err := json.Marshal(cat)
+		if err != nil {
+			fmt.Fprintf(stderr, "errcat-catalog: %s\n", err)
+			return 1
+		}
+		var buf bytes.Buffer
+		json.Indent(&buf, bs, "", "\t")
+		buf.WriteByte('\n')
+		stdout.Write(buf.Bytes())
+	case "markdown", "md":
+		if err := cat.WriteMarkdown(stdout); err != nil {
+			fmt.Fprintf(stderr, "errcat-catalog: %s\n", err)
+			return 1
+		}
+	default:
+		fmt.Fprintf(stderr, "errcat-catalog: unknown format %q\n", *format)
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/warpfork/go-errcat"
+)
+
+// writeTree writes a map of slash-separated paths to contents under dir.
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+var fixtureModule = map[string]string{
+	"go.mod": "module example.com/svc\n",
+	"store/store.go": `package store
+
+import "github.com/warpfork/go-errcat"
+
+// ErrorCategory enumerates the errors of this package.
+type ErrorCategory string
+
+const (
+	// The item does not exist.
+	ErrNotFound = ErrorCategory("store-not-found")
+
+	ErrCorrupt = ErrorCategory("store-corrupt") // Data on disk failed a checksum.
+
+	notACategory = "x"
+)
+
+type Other string
+
+const OtherThing = Other("not-a-category")
+
+func Get() (err error) {
+	defer errcat.RequireErrorHasCategory(&err, ErrorCategory(""))
+	return errcat.Errorf(ErrNotFound, "nope")
+}
+`,
+	"api/api.go": `package api
+
+import (
+	errc "github.com/warpfork/go-errcat"
+
+	"example.com/svc/store"
+)
+
+type Kind string
+
+// Bad input.
+const KindBadRequest Kind = "bad-request"
+
+func Handle() error {
+	return errc.Recategorize(KindBadRequest, store.Get())
+}
+`,
+	"api/api_test.go": `package api
+
+const KindTestOnly Kind = "test-only"
+`,
+	"vendor/example.com/dep/dep.go": `package dep
+
+type Ignored string
+
+const IgnoredThing = Ignored("ignored")
+`,
+}
+
+func TestExtractCatalog(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, fixtureModule)
+
+	cat, err := extractCatalog(filepath.Join(dir, "api"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []errcat.CatalogEntry{
+		{Category: "bad-request", Name: "KindBadRequest", Type: "example.com/svc/api.Kind", Description: "Bad input."},
+		{Category: "store-corrupt", Name: "ErrCorrupt", Type: "example.com/svc/store.ErrorCategory", Description: "Data on disk failed a checksum."},
+		{Category: "store-not-found", Name: "ErrNotFound", Type: "example.com/svc/store.ErrorCategory", Description: "The item does not exist."},
+	}
+	if got := cat.Entries(); !reflect.DeepEqual(got, expect) {
+		t.Errorf("unexpected catalog:\n%#v", got)
+	}
+}
+
+func TestExtractNamespaces(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"go.mod": "module example.com/svc\n",
+		"store/store.go": `package store
+
+import "github.com/warpfork/go-errcat"
+
+type ErrorCategory string
+
+func (ErrorCategory) CategoryNamespace() string { return "store" }
+
+const ErrNotFound = ErrorCategory("not-found")
+
+func Get() error { return errcat.Errorf(ErrNotFound, "nope") }
+`,
+		"auth/auth.go": `package auth
+
+import "github.com/warpfork/go-errcat"
+
+type ErrorCategory string
+
+const ErrNotFound = ErrorCategory("not-found")
+
+func init() {
+	errcat.RegisterNamespace(ErrorCategory(""), errcat.PackageNamespace(ErrorCategory("")))
+}
+
+func Check() error { return errcat.Errorf(ErrNotFound, "who?") }
+`,
+		"billing/billing.go": `package billing
+
+import "github.com/warpfork/go-errcat"
+
+type ErrorCategory string
+
+const ErrUnpaid = ErrorCategory("unpaid")
+
+const namespace = "billing"
+
+func init() {
+	errcat.RegisterNamespace(ErrorCategory(""), namespace)
+}
+
+func Charge() error { return errcat.Errorf(ErrUnpaid, "pay up") }
+`,
+	})
+	cat, err := extractCatalog(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range cat.Entries() {
+		got = append(got, e.Category)
+	}
+	expect := []string{"billing:unpaid", "example.com/svc/auth:not-found", "store:not-found"}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("expected categories %q, got %q", expect, got)
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, fixtureModule)
+
+	t.Run("json", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		if code := run([]string{dir}, &stdout, &stderr); code != 0 {
+			t.Fatalf("exit %d: %s", code, stderr.String())
+		}
+		var cat errcat.Catalog
+		if err := json.Unmarshal(stdout.Bytes(), &cat); err != nil {
+			t.Fatal(err)
+		}
+		if len(cat.Entries()) != 3 {
+			t.Errorf("unexpected catalog: %s", stdout.String())
+		}
+	})
+	t.Run("markdown", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		if code := run([]string{"-format", "markdown", dir}, &stdout, &stderr); code != 0 {
+			t.Fatalf("exit %d: %s", code, stderr.String())
+		}
+		if !strings.Contains(stdout.String(), "### `store-not-found`\n\nDeclared as `ErrNotFound`.\n\nThe item does not exist.\n") {
+			t.Errorf("unexpected markdown:\n%s", stdout.String())
+		}
+	})
+	t.Run("not a module", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		if code := run([]string{t.TempDir()}, &stdout, &stderr); code != 1 {
+			t.Errorf("expected exit 1, got %d", code)
+		}
+	})
+}
@@ -0,0 +1,301 @@
+/*
+	errcat-gen generates the boilerplate for a package's error categories
+	from a small json spec.
+
+	Usage:
+
+		errcat-gen [-spec errcat.json] [-o errcat_gen.go] [-md ERRORS.md]
+
+	It's meant to be run by `go generate`:
+
+		//go:generate errcat-gen -spec errcat.json -o errcat_gen.go -md ERRORS.md
+
+	The spec looks like this:
+
+		{
+			"package": "store",
+			"type": "ErrorCategory",
+			"import_path": "example.com/svc/store",
+			"description": "ErrorCategory enumerates the errors of the store package.",
+			"categories": [
+				{
+					"name": "ErrNotFound",
+					"value": "store-not-found",
+					"description": "The requested item does not exist.",
+					"retryable": false,
+					"http_status": 404,
+					"exit_code": 4,
+					"detail_keys": ["item"]
+				}
+			]
+		}
+
+	From it we generate:
+
+		- the category type, and a const block declaring every category;
+		- String, MarshalText and UnmarshalText methods for the type
+		  (UnmarshalText accepts only the declared values);
+		- an init func registering the categories with errcat.RegisterCategory
+		  and describing them in errcat.DefaultCatalog;
+		- a switch skeleton for callers, in the type's doc comment;
+		- and, if -md is given, a Markdown page documenting the categories.
+
+	"import_path" is optional; it's only used to name the type in the docs
+	and catalog.  Everything else but "package", "type", and the category
+	names and values is optional too.
+*/
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/warpfork/go-errcat"
+)
+
+type spec struct {
+	Package     string         `json:"package"`
+	Type        string         `json:"type"`
+	ImportPath  string         `json:"import_path"`
+	Description string         `json:"description"`
+	Categories  []specCategory `json:"categories"`
+}
+
+type specCategory struct {
+	Name        string   `json:"name"`
+	Value       string   `json:"value"`
+	Description string   `json:"description"`
+	Retryable   bool     `json:"retryable"`
+	HTTPStatus  int      `json:"http_status"`
+	ExitCode    int      `json:"exit_code"`
+	DetailKeys  []string `json:"detail_keys"`
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stderr))
+}
+
+func run(args []string, stderr io.Writer) int {
+	flags := flag.NewFlagSet("errcat-gen", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	specPath := flags.String("spec", "errcat.json", "path of the spec file")
+	goPath := flags.String("o", "errcat_gen.go", "path of the Go file to generate")
+	mdPath := flags.String("md", "", "path of the Markdown docs to generate (none if empty)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 0 {
+		fmt.Fprintln(stderr, "usage: errcat-gen [-spec errcat.json] [-o errcat_gen.go] [-md ERRORS.md]")
+		return 2
+	}
+
+	bs, err := os.ReadFile(*specPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "errcat-gen: %s\n", err)
+		return 1
+	}
+	var s spec
+	if err := json.Unmarshal(bs, &s); err != nil {
+		fmt.Fprintf(stderr, "errcat-gen: %s: %s\n", *specPath, err)
+		return 1
+	}
+	if err := s.validate(); err != nil {
+		fmt.Fprintf(stderr, "errcat-gen: %s: %s\n", *specPath, err)
+		return 1
+	}
+
+	src, err := generateGo(s, filepath.Base(*specPath))
+	if err != nil {
+		fmt.Fprintf(stderr, "errcat-gen: %s\n", err)
+		return 1
+	}
+	if err := os.WriteFile(*goPath, src, 0644); err != nil {
+		fmt.Fprintf(stderr, "errcat-gen: %s\n", err)
+		return 1
+	}
+	if *mdPath != "" {
+		var buf bytes.Buffer
+		if err := s.catalog().WriteMarkdown(&buf); err != nil {
+			fmt.Fprintf(stderr, "errcat-gen: %s\n", err)
+			return 1
+		}
+		if err := os.WriteFile(*mdPath, buf.Bytes(), 0644); err != nil {
+			fmt.Fprintf(stderr, "errcat-gen: %s\n", err)
+			return 1
+		}
+	}
+	return 0
+}
+
+func (s spec) validate() error {
+	if !token.IsIdentifier(s.Package) {
+		return fmt.Errorf("package %q is not a valid identifier", s.Package)
+	}
+	if !token.IsIdentifier(s.Type) {
+		return fmt.Errorf("type %q is not a valid identifier", s.Type)
+	}
+	if len(s.Categories) == 0 {
+		return fmt.Errorf("no categories")
+	}
+	names, values := map[string]bool{}, map[string]bool{}
+	for _, c := range s.Categories {
+		if !token.IsIdentifier(c.Name) {
+			return fmt.Errorf("category name %q is not a valid identifier", c.Name)
+		}
+		if c.Value == "" {
+			return fmt.Errorf("category %s has no value", c.Name)
+		}
+		if names[c.Name] {
+			return fmt.Errorf("category name %s is declared twice", c.Name)
+		}
+		if values[c.Value] {
+			return fmt.Errorf("category value %q is declared twice", c.Value)
+		}
+		names[c.Name], values[c.Value] = true, true
+	}
+	return nil
+}
+
+// Return the package-qualified type name, as errcat catalogs spell it.
+func (s spec) qualifiedType() string {
+	if s.ImportPath == "" {
+		return s.Package + "." + s.Type
+	}
+	return s.ImportPath + "." + s.Type
+}
+
+func (s spec) catalog() *errcat.Catalog {
+	cat := &errcat.Catalog{}
+	for _, c := range s.Categories {
+		cat.AddEntry(errcat.CatalogEntry{
+			Category:    c.Value,
+			Name:        c.Name,
+			Type:        s.qualifiedType(),
+			Description: c.Description,
+			HTTPStatus:  c.HTTPStatus,
+			ExitCode:    c.ExitCode,
+			Retryable:   c.Retryable,
+			DetailKeys:  c.DetailKeys,
+		})
+	}
+	return cat
+}
+
+func generateGo(s spec, specName string) ([]byte, error) {
+	var buf bytes.Buffer
+	err := goTemplate.Execute(&buf, map[string]interface{}{
+		"Spec":     s,
+		"SpecName": specName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated invalid Go (this is a bug): %s\n%s", err, buf.Bytes())
+	}
+	return src, nil
+}
+
+var goTemplate = template.Must(template.New("").Funcs(template.FuncMap{
+	"comment": func(indent string, text string) string {
+		lines := strings.Split(strings.TrimSpace(text), "\n")
+		for i, l := range lines {
+			lines[i] = strings.TrimRight(indent+"// "+l, " ")
+		}
+		return strings.Join(lines, "\n")
+	},
+	"quote": func(s string) string { return fmt.Sprintf("%q", s) },
+}).Parse(`// Code generated by errcat-gen from {{.SpecName}}; DO NOT EDIT.
+
+package {{.Spec.Package}}
+
+import (
+	"fmt"
+
+	"github.com/warpfork/go-errcat"
+)
+
+{{with .Spec.Description}}{{comment "" .}}
+//
+{{else}}// {{.Spec.Type}} enumerates the error categories of this package.
+//
+{{end}}// Callers can handle every category like this:
+//
+//	switch errcat.Category(err) {
+//	case nil:
+//		// success.
+{{- range .Spec.Categories}}
+//	case {{$.Spec.Package}}.{{.Name}}:
+{{- if .Description}}
+{{comment "//\t\t" .Description}}
+{{- end}}
+{{- end}}
+//	default:
+//		panic("bug: unknown error category")
+//	}
+type {{.Spec.Type}} string
+
+const (
+{{- range .Spec.Categories}}
+{{- if .Description}}
+{{comment "\t" .Description}}
+{{- end}}
+	{{.Name}} = {{$.Spec.Type}}({{quote .Value}})
+{{- end}}
+)
+
+// String returns the category's serialized form.
+func (c {{.Spec.Type}}) String() string {
+	return string(c)
+}
+
+// MarshalText returns the category's serialized form.
+func (c {{.Spec.Type}}) MarshalText() ([]byte, error) {
+	return []byte(c), nil
+}
+
+// UnmarshalText accepts only the serialized forms of the declared categories.
+func (c *{{.Spec.Type}}) UnmarshalText(text []byte) error {
+	switch v := {{.Spec.Type}}(text); v {
+	case {{range $i, $c := .Spec.Categories}}{{if $i}}, {{end}}{{$c.Name}}{{end}}:
+		*c = v
+		return nil
+	default:
+		return fmt.Errorf("unknown {{.Spec.Type}} %q", text)
+	}
+}
+
+func init() {
+	errcat.RegisterCategory({{range $i, $c := .Spec.Categories}}{{if $i}}, {{end}}{{$c.Name}}{{end}})
+{{- range .Spec.Categories}}
+	errcat.DefaultCatalog.Add({{.Name}}, errcat.CatalogEntry{
+		Name: {{quote .Name}},
+{{- if .Description}}
+		Description: {{quote .Description}},
+{{- end}}
+{{- if .HTTPStatus}}
+		HTTPStatus: {{.HTTPStatus}},
+{{- end}}
+{{- if .ExitCode}}
+		ExitCode: {{.ExitCode}},
+{{- end}}
+{{- if .Retryable}}
+		Retryable: true,
+{{- end}}
+{{- if .DetailKeys}}
+		DetailKeys: []string{ {{- range $i, $k := .DetailKeys}}{{if $i}}, {{end}}{{quote $k}}{{end -}} },
+{{- end}}
+	})
+{{- end}}
+}
+`))
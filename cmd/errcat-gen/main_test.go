@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const fixtureSpec = `{
+	"package": "store",
+	"type": "ErrorCategory",
+	"import_path": "example.com/svc/store",
+	"categories": [
+		{
+			"name": "ErrNotFound",
+			"value": "store-not-found",
+			"description": "The requested item does not exist.",
+			"http_status": 404,
+			"exit_code": 4,
+			"detail_keys": ["item", "shard"]
+		},
+		{
+			"name": "ErrBusy",
+			"value": "store-busy",
+			"retryable": true,
+			"http_status": 503
+		}
+	]
+}`
+
+const fixtureGo = `// Code generated by errcat-gen from errcat.json; DO NOT EDIT.
+
+package store
+
+import (
+	"fmt"
+
+	"github.com/warpfork/go-errcat"
+)
+
+// ErrorCategory enumerates the error categories of this package.
+//
+// Callers can handle every category like this:
+//
+//	switch errcat.Category(err) {
+//	case nil:
+//		// success.
+//	case store.ErrNotFound:
+//		// The requested item does not exist.
+//	case store.ErrBusy:
+//	default:
+//		panic("bug: unknown error category")
+//	}
+type ErrorCategory string
+
+const (
+	// The requested item does not exist.
+	ErrNotFound = ErrorCategory("store-not-found")
+	ErrBusy     = ErrorCategory("store-busy")
+)
+
+// String returns the category's serialized form.
+func (c ErrorCategory) String() string {
+	return string(c)
+}
+
+// MarshalText returns the category's serialized form.
+func (c ErrorCategory) MarshalText() ([]byte, error) {
+	return []byte(c), nil
+}
+
+// UnmarshalText accepts only the serialized forms of the declared categories.
+func (c *ErrorCategory) UnmarshalText(text []byte) error {
+	switch v := ErrorCategory(text); v {
+	case ErrNotFound, ErrBusy:
+		*c = v
+		return nil
+	default:
+		return fmt.Errorf("unknown ErrorCategory %q", text)
+	}
+}
+
+func init() {
+	errcat.RegisterCategory(ErrNotFound, ErrBusy)
+	errcat.DefaultCatalog.Add(ErrNotFound, errcat.CatalogEntry{
+		Name:        "ErrNotFound",
+		Description: "The requested item does not exist.",
+		HTTPStatus:  404,
+		ExitCode:    4,
+		DetailKeys:  []string{"item", "shard"},
+	})
+	errcat.DefaultCatalog.Add(ErrBusy, errcat.CatalogEntry{
+		Name:       "ErrBusy",
+		HTTPStatus: 503,
+		Retryable:  true,
+	})
+}
+`
+
+const fixtureMarkdown = "# Error categories\n" +
+	"\n## `example.com/svc/store.ErrorCategory`\n" +
+	"\n### `store-busy`\n" +
+	"\nDeclared as `ErrBusy`.\n\n- HTTP status: 503\n- Retryable\n" +
+	"\n### `store-not-found`\n" +
+	"\nDeclared as `ErrNotFound`.\n\nThe requested item does not exist.\n\n- HTTP status: 404\n- Exit code: 4\n- Detail keys: `item`, `shard`\n"
+
+func TestGenerate(t *testing.T) {
+	dir := t.TempDir()
+	specPath := filepath.Join(dir, "errcat.json")
+	if err := os.WriteFile(specPath, []byte(fixtureSpec), 0644); err != nil {
+		t.Fatal(err)
+	}
+	goPath, mdPath := filepath.Join(dir, "errcat_gen.go"), filepath.Join(dir, "ERRORS.md")
+	var stderr bytes.Buffer
+	if code := run([]string{"-spec", specPath, "-o", goPath, "-md", mdPath}, &stderr); code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr.String())
+	}
+	t.Run("go must match fixture", func(t *testing.T) {
+		bs, _ := os.ReadFile(goPath)
+		if string(bs) != fixtureGo {
+			t.Errorf("must match fixture -- got:\n%s", bs)
+		}
+	})
+	t.Run("go must type-check against errcat", func(t *testing.T) {
+		bs, _ := os.ReadFile(goPath)
+		// Parse it as if it were in this directory, so the importer finds errcat the same way this package does.
+		wd, err := os.Getwd()
+		if err != nil {
+			t.Fatal(err)
+		}
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, filepath.Join(wd, "errcat_gen.go"), bs, parser.ParseComments)
+		if err != nil {
+			t.Fatalf("must parse: %s", err)
+		}
+		conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+		if _, err := conf.Check("example.com/svc/store", fset, []*ast.File{f}, nil); err != nil {
+			t.Errorf("must type-check: %s", err)
+		}
+	})
+	t.Run("markdown must match fixture", func(t *testing.T) {
+		bs, _ := os.ReadFile(mdPath)
+		if string(bs) != fixtureMarkdown {
+			t.Errorf("must match fixture -- got:\n%s", bs)
+		}
+	})
+}
+
+func TestSpecValidation(t *testing.T) {
+	for _, tr := range []struct {
+		spec string
+		err  string
+	}{
+		{`{"package":"p","type":"T"}`, "no categories"},
+		{`{"package":"p-q","type":"T","categories":[{"name":"A","value":"a"}]}`, "not a valid identifier"},
+		{`{"package":"p","type":"T","categories":[{"name":"A","value":"a"},{"name":"B","value":"a"}]}`, "declared twice"},
+		{`{"package":"p","type":"T","categories":[{"name":"A"}]}`, "has no value"},
+	} {
+		dir := t.TempDir()
+		specPath := filepath.Join(dir, "errcat.json")
+		os.WriteFile(specPath, []byte(tr.spec), 0644)
+		var stderr bytes.Buffer
+		if code := run([]string{"-spec", specPath, "-o", filepath.Join(dir, "out.go")}, &stderr); code != 1 {
+			t.Errorf("%s: expected exit 1, got %d", tr.spec, code)
+		}
+		if !strings.Contains(stderr.String(), tr.err) {
+			t.Errorf("%s: expected error containing %q, got %q", tr.spec, tr.err, stderr.String())
+		}
+	}
+}
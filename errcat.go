@@ -31,11 +31,15 @@
 	consistent and reliably round-trip-able errors.
 	errcat errors in json should appear as a very simple object:
 
-		{"category":"your_tag", "msg":"full text goes here"}
+		{"category":"your_tag", "message":"full text goes here"}
 
 	If details are present, they're an additional map[string]string:
 
-		{"category":"your_tag", "msg":"full text", "details":{"foo":"bar"}}
+		{"category":"your_tag", "message":"full text", "details":{"foo":"bar"}}
+
+	(Earlier versions of this documentation showed the message under a "msg"
+	key; `errcat.ParseJSON` accepts either, so don't worry if you already
+	have data in that shape.)
 
 	Typical usage patterns involve a const block in each package which
 	enumerates the set of error category values that this package may return.
@@ -76,9 +80,13 @@ package errcat
 
 import (
 	"bytes"
+	"encoding"
 	"fmt"
+	"reflect"
+	"sort"
 	"strings"
 	"text/template"
+	"time"
 )
 
 type Error interface {
@@ -99,24 +107,82 @@ type errStruct struct {
 	Category_ interface{}       `json:"category"          refmt:"category"`
 	Message_  string            `json:"message"           refmt:"message"`
 	Details_  map[string]string `json:"details,omitempty" refmt:"details,omitempty"`
+	extras    *errExtras        // Nil unless the error has any of them.
+}
+
+// The rarely used parts of an error, kept apart so plain errors stay small.  Copies of an error get their own; see `rebase`.
+type errExtras struct {
+	Cause_       error
+	Time_        time.Time
+	Annotations_ []string // Prefixes added to the message, outermost first.
+	Frames_      []Frame  // Where the error was made, if captured.
 }
 
 func (e *errStruct) Category() interface{}      { return e.Category_ }
 func (e *errStruct) Message() string            { return e.Message_ }
 func (e *errStruct) Details() map[string]string { return e.Details_ }
 func (e *errStruct) Error() string              { return e.Message_ }
+func (e *errStruct) Unwrap() error              { return e.cause() }
+
+func (e *errStruct) cause() error {
+	if e.extras == nil {
+		return nil
+	}
+	return e.extras.Cause_
+}
+
+func (e *errStruct) when() time.Time {
+	if e.extras == nil {
+		return time.Time{}
+	}
+	return e.extras.Time_
+}
+
+func (e *errStruct) annotations() []string {
+	if e.extras == nil {
+		return nil
+	}
+	return e.extras.Annotations_
+}
+
+func (e *errStruct) frames() []Frame {
+	if e.extras == nil {
+		return nil
+	}
+	return e.extras.Frames_
+}
+
+// Return the extras for writing, allocating them if need be.  Only for errors still under construction.
+func (e *errStruct) ext() *errExtras {
+	if e.extras == nil {
+		e.extras = &errExtras{}
+	}
+	return e.extras
+}
+
+// Record frames (if there are any to record).  Only for errors still under construction.
+func (e *errStruct) setFrames(frames []Frame) {
+	if frames != nil || e.extras != nil {
+		e.ext().Frames_ = frames
+	}
+}
 
 //
 // Factories
 //    ...
 //
 
+// All factories taking a category check it; see `SetStrictCategories`.
+// They may also record where they were called; see `SetCapture`.
+
 /*
 	Return a new error with the given category, and a message composed of
 	`fmt.Sprintf`'ing the remaining arguments.
 */
 func Errorf(category interface{}, format string, args ...interface{}) error {
-	return &errStruct{category, fmt.Sprintf(format, args...), nil}
+	e := newErrStruct(category, fmt.Sprintf(format, args...), nil)
+	e.setFrames(capturedFrames())
+	return e
 }
 
 /*
@@ -124,15 +190,24 @@ func Errorf(category interface{}, format string, args ...interface{}) error {
 	and a category assigned to the new value.
 
 	If the given error is nil, nil will be returned.
+	The cause and timestamp (see `New`) are kept, as are the type and other
+	fields of your own error types embedding `ErrorBase`; any other error
+	becomes a plain errcat error.
 */
 func Recategorize(category interface{}, err error) error {
-	switch e2 := err.(type) {
+	switch e2 := denil(err, "Recategorize").(type) {
 	case nil:
 		return nil
 	case Error:
-		return &errStruct{category, e2.Message(), e2.Details()}
+		e3 := rebase(e2, NewErrorBase(category, e2.Message(), e2.Details()))
+		if e4, ok := e3.(*errStruct); ok && e4.frames() == nil {
+			e4.setFrames(capturedFrames())
+		}
+		return e3
 	default:
-		return &errStruct{category, e2.Error(), nil}
+		e3 := newErrStruct(category, e2.Error(), nil)
+		e3.setFrames(capturedFrames())
+		return e3
 	}
 }
 
@@ -140,7 +215,9 @@ func Recategorize(category interface{}, err error) error {
 	Return a new error with the given category, message, and details map.
 */
 func ErrorDetailed(category interface{}, msg string, details map[string]string) error {
-	return &errStruct{category, msg, details}
+	e := newErrStruct(category, msg, details)
+	e.setFrames(capturedFrames())
+	return e
 }
 
 /*
@@ -151,8 +228,10 @@ func ErrorDetailed(category interface{}, msg string, details map[string]string)
 	Non-errcat errors are also passed through; the details will be lost (caveat
 	emptor; do not use this method if you haven't already normalized your errors
 	into errcat form).
+	Errors of your own types embedding `ErrorBase` keep their type.
 */
 func AppendDetail(err error, key string, value string) error {
+	err = denil(err, "AppendDetail")
 	switch e2 := err.(type) {
 	case nil:
 		return nil
@@ -162,13 +241,14 @@ func AppendDetail(err error, key string, value string) error {
 			d2[k] = v
 		}
 		d2[key] = value
-		return &errStruct{e2.Category(), e2.Message(), d2}
+		return rebase(e2, ErrorBase{e2.Category(), e2.Message(), d2})
 	default:
 		return err
 	}
 }
 
 func PrefixAnnotate(err error, msg string, details [][2]string) error {
+	err = denil(err, "PrefixAnnotate")
 	switch e2 := err.(type) {
 	case nil:
 		return nil
@@ -201,7 +281,11 @@ func PrefixAnnotate(err error, msg string, details [][2]string) error {
 			d2[v[0]] = v[1]
 		}
 
-		return &errStruct{e2.Category(), buf.String() + ": " + e2.Message(), d2}
+		e3 := rebase(e2, ErrorBase{e2.Category(), buf.String() + ": " + e2.Message(), d2})
+		if e4, ok := e3.(*errStruct); ok {
+			e4.ext().Annotations_ = append([]string{buf.String()}, e4.annotations()...)
+		}
+		return e3
 	default:
 		return err
 	}
@@ -232,16 +316,28 @@ func PrefixAnnotate(err error, msg string, details [][2]string) error {
 		default:
 			panic("bug: unknown error category")
 		}
+
+	This package's own sentinel categories (like `ErrCategoryFilterRejection`)
+	are recognized even when they come from another copy of this package
+	(vendored under a different import path), and are returned as this
+	copy's values, so switches on them work regardless of where the error
+	was made.
 */
 func Category(err error) interface{} {
-	if err == nil {
+	if err = denil(err, "Category"); err == nil {
 		return nil
 	}
 	e, ok := err.(Error)
 	if !ok {
 		return unknown
 	}
-	return e.Category()
+	cat := e.Category()
+	if _, ours := cat.(errorCategory); !ours {
+		if s, ok := cat.(sentinelCategory); ok {
+			return canonicalSentinel(s)
+		}
+	}
+	return cat
 }
 
 /*
@@ -250,7 +346,7 @@ func Category(err error) interface{} {
 	or nil if the error is nil.
 */
 func Details(err error) map[string]string {
-	if err == nil {
+	if err = denil(err, "Details"); err == nil {
 		return nil
 	}
 	e, ok := err.(Error)
@@ -263,4 +359,85 @@ func Details(err error) map[string]string {
 // our internal error categories.  callers should never have a need to reference them.
 type errorCategory string
 
+/*
+	ErrcatSentinel marks our internal categories, in a way that other copies of
+	this package (vendored under different import paths, hence with distinct
+	types) can recognize structurally.
+	It returns the category's serialized form.
+*/
+func (c errorCategory) ErrcatSentinel() string { return string(c) }
+
+type sentinelCategory interface {
+	ErrcatSentinel() string
+}
+
+/*
+	Return this copy's value for a sentinel category from any copy of this package.
+	Sentinels we don't know (from some newer copy, perhaps) are returned as-is.
+*/
+func canonicalSentinel(s sentinelCategory) interface{} {
+	switch c := errorCategory(s.ErrcatSentinel()); c {
+	case unknown, ErrCategoryFilterRejection, ErrDecoding, ErrMissingCategory, ErrInvalidCategory:
+		return c
+	default:
+		return s
+	}
+}
+
 const unknown = errorCategory("unknown-category") // sentinel value for Category() to return on non-errcat errors.
+
+const ErrDecoding = errorCategory("errcat-decoding") // category of errors returned when a serialized errcat error cannot be parsed.
+
+const ErrMissingCategory = errorCategory("errcat-missing-category") // category given to decoded errors whose serial form had no category.
+
+/*
+	Return the string form of a category value, as it should appear when serialized:
+	its value (see `categoryValueString`), qualified with its namespace if
+	it has one (see `RegisterNamespace`).
+*/
+func categoryString(category interface{}) string {
+	s := categoryValueString(category)
+	if ns := namespaceOf(category); ns != "" {
+		return ns + NamespaceSeparator + s
+	}
+	return s
+}
+
+/*
+	Return the string form of a category value, unqualified by any namespace.
+
+	This follows the same precedence as `encoding/json` does for the category
+	field: an `encoding.TextMarshaler` wins, then any value of string kind,
+	then `fmt.Stringer`, and finally plain `fmt.Sprint` as a last resort.
+*/
+func categoryValueString(category interface{}) string {
+	switch c := category.(type) {
+	case nil:
+		return ""
+	case string:
+		return c
+	case errorCategory:
+		return string(c)
+	case encoding.TextMarshaler:
+		if bs, err := c.MarshalText(); err == nil {
+			return string(bs)
+		}
+	}
+	if rv := reflect.ValueOf(category); rv.Kind() == reflect.String {
+		return rv.String()
+	}
+	if c, ok := category.(fmt.Stringer); ok {
+		return c.String()
+	}
+	return fmt.Sprint(category)
+}
+
+// Return the keys of a details map, sorted, for the encoders which must write details in a deterministic order.
+func sortedDetailKeys(details map[string]string) []string {
+	keys := make([]string, 0, len(details))
+	for k := range details {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
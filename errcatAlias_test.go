@@ -0,0 +1,96 @@
+package errcat_test
+
+import (
+	"testing"
+
+	"github.com/warpfork/go-errcat"
+)
+
+type AliasTestCategory string
+type AliasTestLateCategory string
+
+const (
+	ErrAliasCurrent    = AliasTestCategory("alias-current")
+	ErrAliasDeprecated = AliasTestCategory("alias-deprecated")
+	ErrAliasReplaced   = AliasTestCategory("alias-replacement")
+
+	ErrAliasLateDeprecated = AliasTestLateCategory("alias-late-deprecated")
+)
+
+func init() {
+	errcat.RegisterCategory(ErrAliasCurrent, ErrAliasDeprecated, ErrAliasReplaced)
+	errcat.RegisterAlias("alias-old-spelling", ErrAliasCurrent)
+	errcat.RegisterDeprecated(ErrAliasDeprecated, ErrAliasReplaced)
+	errcat.RegisterCategory(ErrAliasLateDeprecated)
+	errcat.RegisterDeprecated(ErrAliasLateDeprecated, ErrAliasReplaced)
+	errcat.RegisterNamespace(AliasTestLateCategory(""), "late-alias")
+}
+
+func TestAliases(t *testing.T) {
+	t.Run("aliases decode to the current category", func(t *testing.T) {
+		e := mustParseJSON(t, `{"category":"alias-old-spelling","message":"hi"}`)
+		shouldCategory(t, e, ErrAliasCurrent)
+		if _, ok := e.Details()[errcat.DetailOriginalCategory]; ok {
+			t.Errorf("must not record original spelling unless asked")
+		}
+	})
+	t.Run("deprecations decode to the replacement and are reported", func(t *testing.T) {
+		var reported [][2]interface{}
+		errcat.SetDeprecationHook(func(deprecated, replacement interface{}) {
+			reported = append(reported, [2]interface{}{deprecated, replacement})
+		})
+		defer errcat.SetDeprecationHook(nil)
+		shouldCategory(t, mustParseJSON(t, `{"category":"alias-deprecated","message":"hi"}`), ErrAliasReplaced)
+		shouldCategory(t, mustParseJSON(t, `{"category":"alias-old-spelling","message":"hi"}`), ErrAliasCurrent)
+		if len(reported) != 1 || reported[0] != [2]interface{}{ErrAliasDeprecated, ErrAliasReplaced} {
+			t.Errorf("must report exactly the deprecation -- got %v", reported)
+		}
+	})
+	t.Run("original spelling can be recorded", func(t *testing.T) {
+		errcat.SetRecordOriginalCategory(true)
+		defer errcat.SetRecordOriginalCategory(false)
+		e, err := errcat.ParseLogfmt(`category=alias-old-spelling msg=hi detail.k=v`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		shouldCategory(t, e, ErrAliasCurrent)
+		if e.Details()[errcat.DetailOriginalCategory] != "alias-old-spelling" || e.Details()["k"] != "v" {
+			t.Errorf("must record original spelling -- got %v", e.Details())
+		}
+		e = mustParseJSON(t, `{"category":"alias-current","message":"hi"}`)
+		if _, ok := e.Details()[errcat.DetailOriginalCategory]; ok {
+			t.Errorf("must not record anything for current spellings")
+		}
+	})
+	t.Run("constructing with deprecated categories is unaffected", func(t *testing.T) {
+		shouldCategory(t, errcat.Errorf(ErrAliasDeprecated, "hi"), ErrAliasDeprecated)
+	})
+	t.Run("aliasing a registered category is rejected", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("must panic")
+			}
+		}()
+		errcat.RegisterAlias("alias-current", ErrAliasReplaced)
+	})
+	t.Run("registering an aliased spelling is rejected", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("must panic")
+			}
+		}()
+		errcat.RegisterCategory(AliasTestCategory("alias-old-spelling"))
+	})
+	t.Run("deprecations follow their category into a namespace", func(t *testing.T) {
+		var reported [][2]interface{}
+		errcat.SetDeprecationHook(func(deprecated, replacement interface{}) {
+			reported = append(reported, [2]interface{}{deprecated, replacement})
+		})
+		defer errcat.SetDeprecationHook(nil)
+		shouldCategory(t, mustParseJSON(t, `{"category":"late-alias:alias-late-deprecated","message":"hi"}`), ErrAliasReplaced)
+		shouldCategory(t, mustParseJSON(t, `{"category":"alias-late-deprecated","message":"hi"}`), ErrAliasReplaced)
+		if len(reported) != 2 {
+			t.Errorf("must report both deprecations -- got %v", reported)
+		}
+	})
+}
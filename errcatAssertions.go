@@ -35,6 +35,7 @@ import (
 	use the `RequireErrorHasCategoryOrPanic` function.
 */
 func RequireErrorHasCategory(e *error, category interface{}) {
+	*e = denil(*e, "RequireErrorHasCategory")
 	if err := requireErrorHasCategory(*e, category); err != nil {
 		*e = err
 	}
@@ -44,6 +45,7 @@ func RequireErrorHasCategory(e *error, category interface{}) {
 	Identical to `RequireErrorHasCategory`, but panics.
 */
 func RequireErrorHasCategoryOrPanic(e *error, category interface{}) {
+	*e = denil(*e, "RequireErrorHasCategoryOrPanic")
 	if err := requireErrorHasCategory(*e, category); err != nil {
 		panic(err)
 	}
@@ -57,6 +59,7 @@ func requireErrorHasCategory(e error, wantCat interface{}) error {
 	case ErrCategoryFilterRejection:
 		// do nothing, because it's already redflagged.
 		// (hm, or should we attach another line number?)
+		// (`Category` recognizes rejections from other copies of this package, too.)
 		return e
 	case unknown:
 		fallthrough
@@ -0,0 +1,123 @@
+package errcat
+
+import (
+	"reflect"
+)
+
+/*
+	ErrorBase is the core of an errcat error -- category, message, and
+	details -- for embedding in your own error types, when you need them
+	to carry more than that:
+
+		type RateLimitedError struct {
+			errcat.ErrorBase
+			RetryAfter time.Duration
+		}
+
+		return &RateLimitedError{errcat.NewErrorBase(ErrRateLimited, "slow down", nil), 5 * time.Second}
+
+	Embedding it gives your type the `Error` interface, and more importantly,
+	lets `Recategorize`, `AppendDetail`, and `PrefixAnnotate` work on it:
+	rather than flattening it into a plain errcat error (and losing your
+	extra fields), they return a copy of your error, of the same type,
+	with only the base changed.
+
+	The copy is shallow, made by copying the struct the pointer points to.
+	That's fine for most types; if yours has fields which mustn't be shared
+	between copies, implement `CloneableError` to copy them yourself.
+	Only pointers to types embedding ErrorBase get this treatment.
+
+	Plain `encoding/json` writes the base's fields inline with those of the
+	type embedding it, so your extra fields go over the wire too -- but it
+	writes the category as json would write that value by itself: without
+	its namespace (see `RegisterNamespace`), and as `{}` for a struct with
+	only a `String` method.  (Decoding likewise leaves the category as a
+	plain string.)  The errcat encoders -- `ErrorBox`, gob, xml, and the
+	rest -- write the canonical form (see `WireVersion`) instead; so where
+	the category matters more than the extra fields, box the error:
+
+		json.Marshal(errcat.Box(err))
+
+	or, to have both, give your type a `MarshalJSON` which does, e.g.:
+
+		func (e *RateLimitedError) MarshalJSON() ([]byte, error) {
+			return json.Marshal(struct {
+				Error      errcat.ErrorBox `json:"error"`
+				RetryAfter time.Duration   `json:"retryAfter"`
+			}{errcat.Box(e), e.RetryAfter})
+		}
+
+	(ErrorBase has no codec methods of its own, since they'd be promoted to
+	your type and hide its other fields.)
+*/
+type ErrorBase struct {
+	Category_ interface{}       `json:"category"          refmt:"category"`
+	Message_  string            `json:"message"           refmt:"message"`
+	Details_  map[string]string `json:"details,omitempty" refmt:"details,omitempty"`
+}
+
+func (e *ErrorBase) Category() interface{}      { return e.Category_ }
+func (e *ErrorBase) Message() string            { return e.Message_ }
+func (e *ErrorBase) Details() map[string]string { return e.Details_ }
+func (e *ErrorBase) Error() string              { return e.Message_ }
+
+func (e *ErrorBase) errcatBase() *ErrorBase { return e }
+
+/*
+	CloneableError may be implemented by types embedding `ErrorBase` to
+	control how they're copied when the factories derive new errors from
+	them.  `CloneError` must return a copy of the error, of the same type,
+	which shares nothing with the original that the original might mutate;
+	the factories then replace the copy's base.
+*/
+type CloneableError interface {
+	Error
+	CloneError() Error
+}
+
+// Implemented by types embedding ErrorBase.  (The method is unexported, so embedding is the only way.)
+type baseEmbedder interface {
+	Error
+	errcatBase() *ErrorBase
+}
+
+/*
+	Return a copy of the error with the given base, of the same type as the
+	original, if it's one of ours or embeds `ErrorBase` and we can copy it;
+	or a new errStruct with the base's contents, if not.
+*/
+func rebase(err Error, base ErrorBase) Error {
+	if e2, ok := err.(*errStruct); ok {
+		e3 := *e2
+		e3.Category_, e3.Message_, e3.Details_ = base.Category_, base.Message_, base.Details_
+		if e2.extras != nil { // The copy may be amended (see Recategorize and PrefixAnnotate), so it gets its own.
+			x := *e2.extras
+			e3.extras = &x
+		}
+		return &e3
+	}
+	if e2, ok := err.(baseEmbedder); ok {
+		if clone := cloneBaseEmbedder(e2); clone != nil {
+			*clone.errcatBase() = base
+			return clone
+		}
+	}
+	return &errStruct{Category_: base.Category_, Message_: base.Message_, Details_: base.Details_}
+}
+
+func cloneBaseEmbedder(err baseEmbedder) baseEmbedder {
+	if c, ok := err.(CloneableError); ok {
+		clone, _ := c.CloneError().(baseEmbedder)
+		if clone == nil || reflect.TypeOf(clone) != reflect.TypeOf(err) {
+			return nil
+		}
+		return clone
+	}
+	rv := reflect.ValueOf(err)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	clone := reflect.New(rv.Elem().Type())
+	clone.Elem().Set(rv.Elem())
+	return clone.Interface().(baseEmbedder)
+}
@@ -0,0 +1,116 @@
+package errcat_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/warpfork/go-errcat"
+)
+
+type rateLimitedError struct {
+	errcat.ErrorBase
+	RetryAfter time.Duration `json:"retryAfter"`
+}
+
+// Has a field which mustn't be shared between copies, so it clones itself.
+type resourceError struct {
+	errcat.ErrorBase
+	ResourceIDs []string
+}
+
+func (e *resourceError) CloneError() errcat.Error {
+	clone := *e
+	clone.ResourceIDs = append([]string(nil), e.ResourceIDs...)
+	return &clone
+}
+
+func TestErrorBase(t *testing.T) {
+	orig := &rateLimitedError{errcat.NewErrorBase(ErrAsdf, "slow down", nil), 5 * time.Second}
+	t.Run("embedding types are errcat errors", func(t *testing.T) {
+		shouldCategory(t, orig, ErrAsdf)
+		if orig.Error() != "slow down" {
+			t.Errorf("got message %q", orig.Error())
+		}
+	})
+	t.Run("factories preserve the type and extra fields", func(t *testing.T) {
+		for name, err := range map[string]error{
+			"Recategorize":   errcat.Recategorize(ErrorCategoryA("a"), orig),
+			"AppendDetail":   errcat.AppendDetail(orig, "k", "v"),
+			"PrefixAnnotate": errcat.PrefixAnnotate(orig, "ctx", [][2]string{{"k", "v"}}),
+		} {
+			e2, ok := err.(*rateLimitedError)
+			if !ok {
+				t.Errorf("%s: expected *rateLimitedError, got %T", name, err)
+				continue
+			}
+			if e2 == orig {
+				t.Errorf("%s: must return a copy, not the original", name)
+			}
+			if e2.RetryAfter != 5*time.Second {
+				t.Errorf("%s: lost the extra field", name)
+			}
+		}
+		shouldCategory(t, errcat.Recategorize(ErrorCategoryA("a"), orig), ErrorCategoryA("a"))
+		if msg := errcat.PrefixAnnotate(orig, "ctx", nil).Error(); msg != "ctx: slow down" {
+			t.Errorf("got message %q", msg)
+		}
+	})
+	t.Run("originals are untouched", func(t *testing.T) {
+		errcat.AppendDetail(orig, "k", "v")
+		errcat.Recategorize(ErrorCategoryA("a"), orig)
+		shouldCategory(t, orig, ErrAsdf)
+		if orig.Details() != nil {
+			t.Errorf("original must keep its details, got %v", orig.Details())
+		}
+	})
+	t.Run("cloneable errors clone themselves", func(t *testing.T) {
+		orig := &resourceError{errcat.NewErrorBase(ErrAsdf, "gone", nil), []string{"r1"}}
+		e2 := errcat.AppendDetail(orig, "k", "v").(*resourceError)
+		e2.ResourceIDs[0] = "changed"
+		if orig.ResourceIDs[0] != "r1" {
+			t.Errorf("clone must not share the slice")
+		}
+		if errcat.Details(e2)["k"] != "v" {
+			t.Errorf("clone must get the new base, got %v", errcat.Details(e2))
+		}
+	})
+	t.Run("bases check their categories", func(t *testing.T) {
+		b := errcat.NewErrorBase(nil, "oops", nil)
+		shouldCategory(t, &b, errcat.ErrInvalidCategory)
+	})
+	t.Run("extra fields survive encoding/json", func(t *testing.T) {
+		bs, err := json.Marshal(orig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(bs) != `{"category":"err-asdf","message":"slow down","retryAfter":5000000000}` {
+			t.Errorf("got %s", bs)
+		}
+		var e rateLimitedError
+		if err := json.Unmarshal(bs, &e); err != nil {
+			t.Fatal(err)
+		}
+		if e.RetryAfter != orig.RetryAfter || e.Message() != "slow down" || e.Category() != "err-asdf" {
+			t.Errorf("must roundtrip -- got %#v", e)
+		}
+	})
+	t.Run("boxed embedders serialize in the canonical form", func(t *testing.T) {
+		for _, tr := range []struct {
+			err    errcat.Error
+			expect string
+		}{
+			{orig, `{"category":"err-asdf","message":"slow down"}`},
+			{&rateLimitedError{ErrorBase: errcat.NewErrorBase(ErrAuthNotFound, "who?", map[string]string{"k": "v"})}, `{"category":"auth:ns-not-found","message":"who?","details":{"k":"v"}}`},
+			{&rateLimitedError{ErrorBase: errcat.NewErrorBase(StringerTestCategory{1}, "hm", nil)}, `{"category":"stringer","message":"hm"}`},
+		} {
+			bs, err := json.Marshal(errcat.Box(tr.err))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(bs) != tr.expect {
+				t.Errorf("got %s, want %s", bs, tr.expect)
+			}
+		}
+	})
+}
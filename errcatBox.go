@@ -0,0 +1,115 @@
+package errcat
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+/*
+	ErrorBox holds an error as a concrete value, for use as a field in structs
+	which get serialized.
+
+	Declaring such fields as `errcat.Error` doesn't work out: an interface
+	field can't be unmarshalled into, and a `*SomeError` field holds typed nils
+	(see `_rsrch/nilly`) that blow up the first time someone asks them for
+	their category.  So declare them as boxes instead:
+
+		type Response struct {
+			Result string          `json:"result,omitempty"`
+			Error  errcat.ErrorBox `json:"error,omitempty"`
+		}
+
+	and use `Box` and `ErrorBox.Err` to get errors in and out.
+
+	The zero value is an empty box.  A box is a slice (of at most one error)
+	only so that `omitempty` omits empty ones, as above; treat it as opaque.
+	Empty boxes serialize as null where they aren't omitted.
+	`Err` and `IsZero` are safe to call on a nil `*ErrorBox`, too, which
+	counts as empty.
+	Full boxes serialize in the canonical form (see `WireVersion`), in json
+	or CBOR; when decoded, categories are restored through the category
+	registry (see `RegisterCategory`), exactly as `ParseJSON` does.
+*/
+type ErrorBox []error // empty, or holding exactly one non-nil error.
+
+/*
+	Return a box holding the error, or an empty box if the error is nil.
+
+	Typed nils count as nil here, regardless of build tags: a box never
+	holds one.
+*/
+func Box(err error) ErrorBox {
+	if err == nil || isTypedNil(err) {
+		return nil
+	}
+	return ErrorBox{err}
+}
+
+/*
+	Return the boxed error, or a true nil if the box is empty (or the
+	pointer is nil).
+
+	Non-errcat errors come back out just as they went in; but after a round
+	trip through serialization, they're errcat errors with the `unknown`
+	category.
+*/
+func (b *ErrorBox) Err() error {
+	if b == nil || len(*b) == 0 {
+		return nil
+	}
+	return (*b)[0]
+}
+
+// IsZero reports whether the box is empty (or the pointer is nil).
+func (b *ErrorBox) IsZero() bool {
+	return b.Err() == nil
+}
+
+// MarshalJSON writes the canonical form, or null for an empty box.
+func (b ErrorBox) MarshalJSON() ([]byte, error) {
+	if b.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(toJSONErr(b[0]))
+}
+
+// UnmarshalJSON decodes tolerantly, exactly like `ParseJSON`.  Null empties the box.
+func (b *ErrorBox) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		*b = nil
+		return nil
+	}
+	e, err := parseJSON(data, false)
+	if err != nil {
+		return err
+	}
+	*b = ErrorBox{e}
+	return nil
+}
+
+/*
+	MarshalCBOR writes the same object as `MarshalJSON`, in deterministically
+	encoded CBOR (or a CBOR null, for an empty box).
+	The method matches what CBOR libraries like `github.com/fxamacker/cbor`
+	look for, so boxes in your structs just work with them.
+*/
+func (b ErrorBox) MarshalCBOR() ([]byte, error) {
+	if b.IsZero() {
+		return []byte{cborNull}, nil
+	}
+	return appendCBORErr(nil, toJSONErr(b[0])), nil
+}
+
+// UnmarshalCBOR decodes tolerantly, exactly like `UnmarshalJSON`.  Null (or undefined) empties the box.
+func (b *ErrorBox) UnmarshalCBOR(data []byte) error {
+	if len(data) == 1 && (data[0] == cborNull || data[0] == cborSimple|23) {
+		*b = nil
+		return nil
+	}
+	e, err := parseCBOR(data)
+	if err != nil {
+		return err
+	}
+	*b = ErrorBox{e}
+	return nil
+}
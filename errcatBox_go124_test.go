@@ -0,0 +1,26 @@
+//go:build go1.24
+// +build go1.24
+
+package errcat_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/warpfork/go-errcat"
+)
+
+// encoding/json only learned `omitzero` in Go 1.24; before that, the option is ignored.
+func TestErrorBoxOmitzero(t *testing.T) {
+	type response struct {
+		Result string          `json:"result,omitempty"`
+		Error  errcat.ErrorBox `json:"error,omitzero"`
+	}
+	bs, err := json.Marshal(response{Result: "ok"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(bs) != `{"result":"ok"}` {
+		t.Errorf("empty boxes must be omitted with omitzero -- got %s", bs)
+	}
+}
@@ -0,0 +1,191 @@
+package errcat_test
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/warpfork/go-errcat"
+)
+
+type boxedResponse struct {
+	Result string          `json:"result,omitempty"`
+	Error  errcat.ErrorBox `json:"error,omitempty"`
+}
+
+type boxedResponseNoOmit struct {
+	Error errcat.ErrorBox `json:"error"`
+}
+
+func TestErrorBox(t *testing.T) {
+	t.Run("empty boxes yield true nils", func(t *testing.T) {
+		var b errcat.ErrorBox
+		if err := b.Err(); err != nil {
+			t.Errorf("expected nil, got %#v", err)
+		}
+		b = errcat.Box(nil)
+		if !b.IsZero() {
+			t.Errorf("boxing nil must make an empty box")
+		}
+		var typedNil *otherCopyError
+		b = errcat.Box(typedNil)
+		if err := b.Err(); err != nil {
+			t.Errorf("boxing a typed nil must make an empty box, got %#v", err)
+		}
+	})
+	t.Run("nil box pointers are empty", func(t *testing.T) {
+		var b *errcat.ErrorBox
+		if err := b.Err(); err != nil {
+			t.Errorf("expected nil, got %#v", err)
+		}
+		if !b.IsZero() {
+			t.Errorf("a nil box pointer must be empty")
+		}
+	})
+	t.Run("full boxes give back what went in", func(t *testing.T) {
+		err := errcat.Errorf(ErrAsdf, "hi")
+		b := errcat.Box(err)
+		if b.Err() != err || b.IsZero() {
+			t.Errorf("must be the same error")
+		}
+	})
+	t.Run("json", func(t *testing.T) {
+		t.Run("empty boxes are omitted with omitempty", func(t *testing.T) {
+			for _, r := range []boxedResponse{{Result: "ok"}, {Result: "ok", Error: errcat.Box(nil)}, {Result: "ok", Error: errcat.ErrorBox{}}} {
+				bs, err := json.Marshal(r)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(bs) != `{"result":"ok"}` {
+					t.Errorf("got %s", bs)
+				}
+			}
+		})
+		t.Run("empty boxes are null otherwise", func(t *testing.T) {
+			bs, err := json.Marshal(boxedResponseNoOmit{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(bs) != `{"error":null}` {
+				t.Errorf("got %s", bs)
+			}
+			var r boxedResponseNoOmit
+			if err := json.Unmarshal(bs, &r); err != nil {
+				t.Fatal(err)
+			}
+			if r.Error.Err() != nil {
+				t.Errorf("expected nil, got %#v", r.Error.Err())
+			}
+		})
+		t.Run("full boxes round trip, with registered categories", func(t *testing.T) {
+			bs, err := json.Marshal(boxedResponse{Error: errcat.Box(errcat.ErrorDetailed(ErrXMLRegistered, "hi", map[string]string{"k": "v"}))})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(bs) != `{"error":{"category":"err-xml","message":"hi","details":{"k":"v"}}}` {
+				t.Errorf("got %s", bs)
+			}
+			var r boxedResponse
+			if err := json.Unmarshal(bs, &r); err != nil {
+				t.Fatal(err)
+			}
+			shouldCategory(t, r.Error.Err(), ErrXMLRegistered)
+			if d := errcat.Details(r.Error.Err()); !reflect.DeepEqual(d, map[string]string{"k": "v"}) {
+				t.Errorf("got details %v", d)
+			}
+		})
+		t.Run("decoding errors are reported", func(t *testing.T) {
+			var r boxedResponse
+			err := json.Unmarshal([]byte(`{"error":["nope"]}`), &r)
+			shouldCategory(t, err, errcat.ErrDecoding)
+		})
+	})
+	t.Run("cbor", func(t *testing.T) {
+		t.Run("encodes deterministically", func(t *testing.T) {
+			bs, err := errcat.Box(errcat.ErrorDetailed(ErrXMLRegistered, "hi", map[string]string{"k": "v", "aa": "b"})).MarshalCBOR()
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := "a3" +
+				"6764657461696c73" + "a2" + "616b" + "6176" + "626161" + "6162" + // "details": {"k": "v", "aa": "b"}
+				"676d657373616765" + "626869" + // "message": "hi"
+				"6863617465676f7279" + "676572722d786d6c" // "category": "err-xml"
+			if hex.EncodeToString(bs) != want {
+				t.Errorf("got  %x\nwant %s", bs, want)
+			}
+			var b errcat.ErrorBox
+			if err := b.UnmarshalCBOR(bs); err != nil {
+				t.Fatal(err)
+			}
+			shouldCategory(t, b.Err(), ErrXMLRegistered)
+			if d := errcat.Details(b.Err()); !reflect.DeepEqual(d, map[string]string{"k": "v", "aa": "b"}) {
+				t.Errorf("got details %v", d)
+			}
+		})
+		t.Run("causes and times round trip", func(t *testing.T) {
+			when := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+			bs, err := errcat.Box(errcat.New(ErrXMLRegistered, "outer", errcat.WithCause(errcat.Errorf(ErrXMLRegistered, "inner")), errcat.WithTime(when))).MarshalCBOR()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var b errcat.ErrorBox
+			if err := b.UnmarshalCBOR(bs); err != nil {
+				t.Fatal(err)
+			}
+			if !errcat.Timestamp(b.Err()).Equal(when) {
+				t.Errorf("lost the time")
+			}
+			cause := errors.Unwrap(b.Err())
+			shouldCategory(t, cause, ErrXMLRegistered)
+			if cause == nil || cause.Error() != "inner" {
+				t.Errorf("lost the cause: %v", cause)
+			}
+		})
+		t.Run("empty boxes are null", func(t *testing.T) {
+			bs, _ := errcat.Box(nil).MarshalCBOR()
+			if hex.EncodeToString(bs) != "f6" {
+				t.Errorf("got %x", bs)
+			}
+			b := errcat.Box(errcat.Errorf(ErrAsdf, "full"))
+			if err := b.UnmarshalCBOR(bs); err != nil {
+				t.Fatal(err)
+			}
+			if !b.IsZero() {
+				t.Errorf("null must empty the box")
+			}
+		})
+		t.Run("decodes what other encoders write, tolerantly", func(t *testing.T) {
+			data := "a3" +
+				"636d7367" + "626869" + // "msg": "hi"
+				"6863617465676f7279" + "c0" + "676572722d786d6c" + // "category": tag(0) "err-xml"
+				"6764657461696c73" + "a3" + "616e" + "0c" + "616d" + "20" + "6166" + "f93e00" // "details": {"n": 12, "m": -1, "f": 1.5}
+			bs, _ := hex.DecodeString(data)
+			var b errcat.ErrorBox
+			if err := b.UnmarshalCBOR(bs); err != nil {
+				t.Fatal(err)
+			}
+			shouldCategory(t, b.Err(), ErrXMLRegistered)
+			if b.Err().Error() != "hi" {
+				t.Errorf("got message %q", b.Err().Error())
+			}
+			if d := errcat.Details(b.Err()); !reflect.DeepEqual(d, map[string]string{"n": "12", "m": "-1", "f": "1.5"}) {
+				t.Errorf("got details %v", d)
+			}
+		})
+		t.Run("garbage is rejected", func(t *testing.T) {
+			for _, data := range []string{"", "a1", "a16163", "63616263", "a0ff", "9f", "a0a0"} {
+				bs, _ := hex.DecodeString(data)
+				var b errcat.ErrorBox
+				err := b.UnmarshalCBOR(bs)
+				shouldCategory(t, err, errcat.ErrDecoding)
+				if err != nil && !strings.HasPrefix(err.Error(), "errcat: invalid cbor") {
+					t.Errorf("%s: got message %q", data, err.Error())
+				}
+			}
+		})
+	})
+}
@@ -0,0 +1,104 @@
+package errcat
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+)
+
+/*
+	Capture says how much of the stack to record where errors are made.
+*/
+type Capture int32
+
+const (
+	CaptureNone   Capture = iota // Record nothing.  The default.
+	CaptureCaller                // Record the frame which made the error.
+	CaptureStack                 // Record the whole stack (up to `MaxCapturedFrames`), innermost first.
+)
+
+func (c Capture) String() string {
+	switch c {
+	case CaptureNone:
+		return "none"
+	case CaptureCaller:
+		return "caller"
+	case CaptureStack:
+		return "stack"
+	default:
+		return fmt.Sprintf("Capture(%d)", int32(c))
+	}
+}
+
+// MaxCapturedFrames limits how much of the stack `CaptureStack` records.
+const MaxCapturedFrames = 32
+
+/*
+	Set how much of the stack `Errorf`, `ErrorDetailed`, `Recategorize`,
+	and `New` record, package-wide.
+	It's off by default, because it's not free: capturing even just the
+	caller makes constructing an error a few dozen times slower
+	(microseconds rather than nanoseconds; see `BenchmarkCapture`).
+
+	Individual calls to `New` can ask for more (or less) with `WithCaller`
+	and `WithStack`.
+	`Recategorize` keeps the frames of errors which already have them, since
+	where the error was born is more interesting than where it was recategorized.
+
+	Captured frames are returned by `Frames`, printed by the `%+v` format,
+	and serialized by `DebugJSON` -- but never by the regular serializations,
+	since file paths are nobody's business but yours.
+*/
+func SetCapture(mode Capture) {
+	atomic.StoreInt32(&captureMode, int32(mode))
+}
+
+var captureMode int32
+
+// Return the frames to record for the package-wide capture mode, if any.
+func capturedFrames() []Frame {
+	switch Capture(atomic.LoadInt32(&captureMode)) {
+	case CaptureCaller:
+		return captureFrames(1)
+	case CaptureStack:
+		return captureFrames(MaxCapturedFrames)
+	default:
+		return nil
+	}
+}
+
+/*
+	Record the stack where `New` was called (up to `MaxCapturedFrames`),
+	innermost first; see `Frames`.
+*/
+func WithStack() Option {
+	return func(e *errStruct) {
+		e.setFrames(captureFrames(MaxCapturedFrames))
+	}
+}
+
+/*
+	Record nothing of the stack, regardless of `SetCapture`.
+*/
+func WithoutCapture() Option {
+	return func(e *errStruct) {
+		e.setFrames([]Frame{})
+	}
+}
+
+/*
+	Return the json form of the error (see `WireVersion`), plus a "frames"
+	array holding any frames captured where it -- and each of its causes --
+	was made:
+
+		{"category":"...", "message":"...", "frames":[{"function":"...", "file":"...", "line":12}]}
+
+	This is for logs and debugging, not for sending to anyone else:
+	`ParseJSON` reads it (ignoring the frames), but `ParseJSONStrict` rejects it.
+*/
+func DebugJSON(err error) ([]byte, error) {
+	if err = denil(err, "DebugJSON"); err == nil {
+		return []byte("null"), nil
+	}
+	return json.Marshal(toDebugJSONErr(err))
+}
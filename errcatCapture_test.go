@@ -0,0 +1,125 @@
+package errcat_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/warpfork/go-errcat"
+)
+
+func setCapture(t *testing.T, mode errcat.Capture) {
+	errcat.SetCapture(mode)
+	t.Cleanup(func() { errcat.SetCapture(errcat.CaptureNone) })
+}
+
+func shouldBeFromHere(t *testing.T, frame errcat.Frame) {
+	t.Helper()
+	if filepath.Base(frame.File) != "errcatCapture_test.go" || !strings.HasPrefix(frame.Function, "github.com/warpfork/go-errcat_test.TestCapture") {
+		t.Errorf("frame must point here, got %v", frame)
+	}
+}
+
+func TestCapture(t *testing.T) {
+	t.Run("nothing by default", func(t *testing.T) {
+		if frames := errcat.Frames(errcat.Errorf(ErrAsdf, "msg")); frames != nil {
+			t.Errorf("expected no frames, got %v", frames)
+		}
+	})
+	t.Run("caller, package-wide", func(t *testing.T) {
+		setCapture(t, errcat.CaptureCaller)
+		for name, err := range map[string]error{
+			"Errorf":        errcat.Errorf(ErrAsdf, "msg"),
+			"ErrorDetailed": errcat.ErrorDetailed(ErrAsdf, "msg", nil),
+			"Recategorize":  errcat.Recategorize(ErrAsdf, fmt.Errorf("plain")),
+			"New":           errcat.New(ErrAsdf, "msg"),
+		} {
+			frames := errcat.Frames(err)
+			if len(frames) != 1 {
+				t.Errorf("%s: expected 1 frame, got %v", name, frames)
+				continue
+			}
+			shouldBeFromHere(t, frames[0])
+		}
+	})
+	t.Run("stack, package-wide", func(t *testing.T) {
+		setCapture(t, errcat.CaptureStack)
+		frames := errcat.Frames(errcat.Errorf(ErrAsdf, "msg"))
+		if len(frames) < 2 {
+			t.Fatalf("expected a stack, got %v", frames)
+		}
+		shouldBeFromHere(t, frames[0])
+	})
+	t.Run("per call", func(t *testing.T) {
+		if frames := errcat.Frames(errcat.New(ErrAsdf, "msg", errcat.WithStack())); len(frames) < 2 {
+			t.Errorf("expected a stack, got %v", frames)
+		}
+		setCapture(t, errcat.CaptureStack)
+		if frames := errcat.Frames(errcat.New(ErrAsdf, "msg", errcat.WithoutCapture())); len(frames) != 0 {
+			t.Errorf("expected no frames, got %v", frames)
+		}
+	})
+	t.Run("recategorizing keeps the origin", func(t *testing.T) {
+		orig := errcat.New(ErrAsdf, "msg", errcat.WithCaller())
+		setCapture(t, errcat.CaptureStack)
+		err := errcat.Recategorize(ErrorCategoryA("a"), orig)
+		if frames := errcat.Frames(err); len(frames) != 1 || frames[0] != errcat.Frames(orig)[0] {
+			t.Errorf("expected the original frame, got %v", frames)
+		}
+	})
+	t.Run("formatting", func(t *testing.T) {
+		err := errcat.New(ErrAsdf, "msg", errcat.WithCaller())
+		if s := fmt.Sprintf("%v|%s|%q", err, err, err); s != `msg|msg|"msg"` {
+			t.Errorf("got %q", s)
+		}
+		s := fmt.Sprintf("%+v", err)
+		if !strings.HasPrefix(s, "msg\n") || !strings.Contains(s, "\n\tat github.com/warpfork/go-errcat_test.TestCapture") || !strings.Contains(s, "errcatCapture_test.go:") {
+			t.Errorf("got %q", s)
+		}
+	})
+	t.Run("debug json", func(t *testing.T) {
+		err := errcat.New(ErrXMLRegistered, "msg", errcat.WithCaller(), errcat.WithCause(errcat.New(ErrXMLRegistered, "inner", errcat.WithCaller())))
+		bs, _ := json.Marshal(err)
+		if strings.Contains(string(bs), "frames") {
+			t.Errorf("regular json must not have frames: %s", bs)
+		}
+		bs, e2 := errcat.DebugJSON(err)
+		if e2 != nil {
+			t.Fatal(e2)
+		}
+		var debug struct {
+			Frames []errcat.Frame
+			Cause  struct {
+				Frames []errcat.Frame
+			}
+		}
+		if err := json.Unmarshal(bs, &debug); err != nil {
+			t.Fatal(err)
+		}
+		if len(debug.Frames) != 1 || len(debug.Cause.Frames) != 1 {
+			t.Fatalf("expected frames for error and cause, got %s", bs)
+		}
+		shouldBeFromHere(t, debug.Frames[0])
+		if _, err := errcat.ParseJSON(bs); err != nil {
+			t.Errorf("lenient parse must accept the debug form: %v", err)
+		}
+		_, err = errcat.ParseJSONStrict(bs)
+		if rule := errcat.Details(err)["rule"]; rule != "unknown-field" {
+			t.Errorf("strict parse must reject the debug form, got %v", err)
+		}
+	})
+}
+
+func BenchmarkCapture(b *testing.B) {
+	for _, mode := range []errcat.Capture{errcat.CaptureNone, errcat.CaptureCaller, errcat.CaptureStack} {
+		b.Run(fmt.Sprint(mode), func(b *testing.B) {
+			errcat.SetCapture(mode)
+			defer errcat.SetCapture(errcat.CaptureNone)
+			for n := 0; n < b.N; n++ {
+				errcat.ErrorDetailed(ErrAsdf, "msg", nil)
+			}
+		})
+	}
+}
@@ -0,0 +1,397 @@
+package errcat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+/*
+	A Catalog is a machine-readable list of error categories: what they are,
+	what they mean, and how they should be presented.
+
+	Packages describe their categories in a catalog (usually `DefaultCatalog`)
+	from an `init` func, next to the const block declaring them:
+
+		func init() {
+			errcat.DefaultCatalog.Add(ErrNotFound, errcat.CatalogEntry{
+				Description: "The requested item does not exist.",
+				HTTPStatus:  404,
+				ExitCode:    4,
+				DetailKeys:  []string{"item"},
+			})
+		}
+
+	The catalog can then be published to API consumers: as json, or as a
+	JSON Schema for the errcat json object (see `JSONSchema`).
+*/
+type Catalog struct {
+	mu      sync.Mutex
+	entries []CatalogEntry
+}
+
+/*
+	CatalogEntry describes one error category.
+	It's also the json form of entries in a serialized catalog.
+*/
+type CatalogEntry struct {
+	Category    string   `json:"category"`              // The category, in its serialized string form.
+	Name        string   `json:"name,omitempty"`        // The Go identifier of the const declaring the category, if known.
+	Type        string   `json:"type,omitempty"`        // The package-qualified Go type of the category.
+	Description string   `json:"description,omitempty"` // Human-readable explanation of what the category means.
+	HTTPStatus  int      `json:"http_status,omitempty"` // HTTP status code errors of this category should be presented with.
+	ExitCode    int      `json:"exit_code,omitempty"`   // Process exit code errors of this category should be presented with.
+	Retryable   bool     `json:"retryable,omitempty"`   // Whether the operation that failed with this category may succeed if retried.
+	Severity    Severity `json:"severity,omitempty"`    // How bad errors of this category are.
+	DetailKeys  []string `json:"detail_keys,omitempty"` // Keys of details errors of this category may carry.
+}
+
+/*
+	DefaultCatalog is the catalog packages should describe their categories in,
+	unless they have reason to keep one of their own.
+
+	It starts out describing this package's own categories.
+*/
+var DefaultCatalog = &Catalog{entries: []CatalogEntry{
+	{Category: string(unknown), Type: "errcat.errorCategory", Description: "The error was not an errcat error, so has no category.", HTTPStatus: 500, ExitCode: 1},
+	{Category: string(ErrCategoryFilterRejection), Type: "errcat.errorCategory", Description: "An error of an unexpected category reached a RequireErrorHasCategory filter; this is a bug.", HTTPStatus: 500, ExitCode: 1, Severity: SeverityCritical},
+	{Category: string(ErrDecoding), Type: "errcat.errorCategory", Description: "A serialized errcat error could not be parsed.", HTTPStatus: 500, ExitCode: 1},
+	{Category: string(ErrMissingCategory), Type: "errcat.errorCategory", Description: "A serialized errcat error was decoded, but had no category.", HTTPStatus: 500, ExitCode: 1},
+	{Category: string(ErrInvalidCategory), Type: "errcat.errorCategory", Description: "An error was constructed with a category which is not comparable or not serializable as a string; this is a bug.", HTTPStatus: 500, ExitCode: 1, Severity: SeverityCritical},
+}}
+
+/*
+	Add an entry describing the given category value.
+	The entry's Category and Type are filled in from the value.
+
+	Adding an entry for a category (and type) already in the catalog
+	replaces the earlier entry.
+*/
+func (c *Catalog) Add(category interface{}, entry CatalogEntry) {
+	entry.Category = categoryString(category)
+	entry.Type = typeString(reflect.TypeOf(category))
+	c.AddEntry(entry)
+}
+
+/*
+	Add an entry as-is.  This is mostly for tools assembling catalogs from
+	sources other than live category values; prefer `Add`.
+
+	Adding an entry for a category (and type) already in the catalog
+	replaces the earlier entry.
+*/
+func (c *Catalog) AddEntry(entry CatalogEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, e := range c.entries {
+		if e.Category == entry.Category && e.Type == entry.Type {
+			c.entries[i] = entry
+			return
+		}
+	}
+	c.entries = append(c.entries, entry)
+}
+
+/*
+	Return the entry for the category value, and whether there was one.
+*/
+func (c *Catalog) Lookup(category interface{}) (CatalogEntry, bool) {
+	cat, typ := categoryString(category), typeString(reflect.TypeOf(category))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, e := range c.entries {
+		if e.Category == cat && e.Type == typ {
+			return e, true
+		}
+	}
+	return CatalogEntry{}, false
+}
+
+// Re-qualify the entries of the given type, whose namespace has changed from oldNs (which may be empty) to newNs.
+func (c *Catalog) requalify(typ string, oldNs, newNs string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, e := range c.entries {
+		if e.Type != typ {
+			continue
+		}
+		v := e.Category
+		if oldNs != "" {
+			v = strings.TrimPrefix(v, oldNs+NamespaceSeparator)
+		}
+		c.entries[i].Category = newNs + NamespaceSeparator + v
+	}
+}
+
+/*
+	Return a copy of the catalog's entries, sorted by category
+	(and then by type, in the unhappy event of two categories with the
+	same string form).
+*/
+func (c *Catalog) Entries() []CatalogEntry {
+	c.mu.Lock()
+	entries := append([]CatalogEntry(nil), c.entries...)
+	c.mu.Unlock()
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Category != entries[j].Category {
+			return entries[i].Category < entries[j].Category
+		}
+		return entries[i].Type < entries[j].Type
+	})
+	return entries
+}
+
+type jsonCatalog struct {
+	Categories []CatalogEntry `json:"categories"`
+}
+
+/*
+	MarshalJSON writes the catalog as `{"categories":[...]}`, with entries
+	sorted as by `Entries`.
+*/
+func (c *Catalog) MarshalJSON() ([]byte, error) {
+	entries := c.Entries()
+	if entries == nil {
+		entries = []CatalogEntry{}
+	}
+	return json.Marshal(jsonCatalog{entries})
+}
+
+func (c *Catalog) UnmarshalJSON(data []byte) error {
+	var j jsonCatalog
+	if err := json.Unmarshal(data, &j); err != nil {
+		return Errorf(ErrDecoding, "errcat: malformed catalog: %s", err)
+	}
+	c.mu.Lock()
+	c.entries = j.Categories
+	c.mu.Unlock()
+	return nil
+}
+
+/*
+	Return a JSON Schema (draft 2020-12) for the errcat json object,
+	with the category constrained to an enum of every category in the catalog.
+
+	Output is deterministic, so it's suitable for checking in and diffing
+	in API contract tests.
+*/
+func (c *Catalog) JSONSchema() ([]byte, error) {
+	entries := c.Entries()
+	enum := make([]string, 0, len(entries))
+	for i, e := range entries {
+		if i > 0 && e.Category == entries[i-1].Category {
+			continue
+		}
+		enum = append(enum, e.Category)
+	}
+	schema := errorSchema(enum)
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "errcat error"
+	schema["properties"].(map[string]interface{})["v"] = map[string]interface{}{
+		"description": "Wire format version.  Absent means 1.",
+		"type":        "integer",
+		"minimum":     1,
+		"maximum":     WireVersion,
+	}
+	// Causes may come from anywhere, so their categories aren't limited to the catalog's.
+	schema["$defs"] = map[string]interface{}{
+		"cause": errorSchema(nil),
+	}
+	return json.MarshalIndent(schema, "", "\t")
+}
+
+// The schema of an error object (without the version marker), with the category limited to the enum if it's non-nil.
+func errorSchema(enum []string) map[string]interface{} {
+	category := map[string]interface{}{
+		"description": "The error category.  Handling logic should branch on this.",
+		"type":        "string",
+	}
+	if enum != nil {
+		category["enum"] = enum
+	}
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"category": category,
+			"message": map[string]interface{}{
+				"description": "Human-readable description of the error.",
+				"type":        "string",
+			},
+			"details": map[string]interface{}{
+				"description": "Optional key-value details.",
+				"type":        "object",
+				"additionalProperties": map[string]interface{}{
+					"type": "string",
+				},
+			},
+			"cause": map[string]interface{}{
+				"description": "The error that caused this one.  (Version 2 and up.)",
+				"$ref":        "#/$defs/cause",
+			},
+			"time": map[string]interface{}{
+				"description": "When the error happened.  (Version 2 and up.)",
+				"type":        "string",
+				"format":      "date-time",
+			},
+		},
+		"required":             []string{"category", "message"},
+		"additionalProperties": false,
+	}
+}
+
+/*
+	Return the package-qualified name of a type, e.g. "github.com/foo/bar.ErrorCategory".
+	Our own types are always spelled "errcat.*", so catalogs don't vary
+	depending on where this package was vendored.
+*/
+func typeString(rt reflect.Type) string {
+	switch {
+	case rt == nil:
+		return ""
+	case rt.Name() == "" || rt.PkgPath() == "":
+		return rt.String()
+	case rt.PkgPath() == reflect.TypeOf(unknown).PkgPath():
+		return "errcat." + rt.Name()
+	default:
+		return rt.PkgPath() + "." + rt.Name()
+	}
+}
+
+/*
+	Write the catalog as a Markdown document: a section per category type,
+	and an entry per category with its description and presentation.
+*/
+func (c *Catalog) WriteMarkdown(w io.Writer) error {
+	entries := c.Entries()
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Type < entries[j].Type })
+	var buf bytes.Buffer
+	buf.WriteString("# Error categories\n")
+	for i, e := range entries {
+		if i == 0 || e.Type != entries[i-1].Type {
+			typ := e.Type
+			if typ == "" {
+				typ = "(untyped)"
+			}
+			fmt.Fprintf(&buf, "\n## `%s`\n", typ)
+		}
+		var paras, bullets []string
+		if e.Name != "" {
+			paras = append(paras, fmt.Sprintf("Declared as `%s`.", e.Name))
+		}
+		if e.Description != "" {
+			paras = append(paras, strings.TrimSpace(e.Description))
+		}
+		if e.HTTPStatus != 0 {
+			bullets = append(bullets, fmt.Sprintf("- HTTP status: %d", e.HTTPStatus))
+		}
+		if e.ExitCode != 0 {
+			bullets = append(bullets, fmt.Sprintf("- Exit code: %d", e.ExitCode))
+		}
+		if e.Retryable {
+			bullets = append(bullets, "- Retryable")
+		}
+		if e.Severity != SeverityUnspecified {
+			bullets = append(bullets, fmt.Sprintf("- Severity: %s", e.Severity))
+		}
+		if len(e.DetailKeys) > 0 {
+			bullets = append(bullets, fmt.Sprintf("- Detail keys: `%s`", strings.Join(e.DetailKeys, "`, `")))
+		}
+		if len(bullets) > 0 {
+			paras = append(paras, strings.Join(bullets, "\n"))
+		}
+		fmt.Fprintf(&buf, "\n### `%s`\n", e.Category)
+		if len(paras) > 0 {
+			fmt.Fprintf(&buf, "\n%s\n", strings.Join(paras, "\n\n"))
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+/*
+	CatalogDiff describes how a catalog changed between two versions.
+
+	Removing, renaming, or retyping a category is a breaking change: any
+	caller switching on the old value (or decoding it from old data) will
+	stop matching.  Adding a category is not.
+*/
+type CatalogDiff struct {
+	Removed []CatalogEntry  // Categories in the old catalog with no counterpart in the new.
+	Renamed []CatalogChange // Consts (same type and name) whose category value changed.
+	Retyped []CatalogChange // Category values which are now declared with a different type.
+	Added   []CatalogEntry  // Categories in the new catalog with no counterpart in the old.
+}
+
+// CatalogChange pairs the old and new entries of a category that changed.
+type CatalogChange struct {
+	Old CatalogEntry
+	New CatalogEntry
+}
+
+// Breaking reports whether any categories were removed, renamed, or retyped.
+func (d CatalogDiff) Breaking() bool {
+	return len(d.Removed) > 0 || len(d.Renamed) > 0 || len(d.Retyped) > 0
+}
+
+/*
+	Compare two catalogs.
+
+	Entries match if they have the same category and type; changes to
+	descriptions and presentation don't count as differences.
+	Of the entries which don't match, an old and new entry with the same
+	type and const name are a rename, and an old and new entry with the
+	same category value are a retype; anything left over was removed or added.
+*/
+func DiffCatalogs(from, to *Catalog) CatalogDiff {
+	var d CatalogDiff
+	olds, news := from.Entries(), to.Entries()
+	matched := make([]bool, len(news))
+	var unmatched []CatalogEntry
+	for _, o := range olds {
+		found := false
+		for j, n := range news {
+			if !matched[j] && n.Category == o.Category && n.Type == o.Type {
+				matched[j], found = true, true
+				break
+			}
+		}
+		if !found {
+			unmatched = append(unmatched, o)
+		}
+	}
+	for _, o := range unmatched {
+		change := -1
+		for j, n := range news {
+			if !matched[j] && o.Name != "" && n.Type == o.Type && n.Name == o.Name {
+				change = j
+				d.Renamed = append(d.Renamed, CatalogChange{o, n})
+				break
+			}
+		}
+		if change < 0 {
+			for j, n := range news {
+				if !matched[j] && n.Category == o.Category {
+					change = j
+					d.Retyped = append(d.Retyped, CatalogChange{o, n})
+					break
+				}
+			}
+		}
+		if change < 0 {
+			d.Removed = append(d.Removed, o)
+			continue
+		}
+		matched[change] = true
+	}
+	for j, n := range news {
+		if !matched[j] {
+			d.Added = append(d.Added, n)
+		}
+	}
+	return d
+}
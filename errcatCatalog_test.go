@@ -0,0 +1,188 @@
+package errcat_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/warpfork/go-errcat"
+)
+
+type CatalogTestCategory string
+
+const (
+	ErrCatalogNotFound = CatalogTestCategory("err-not-found")
+	ErrCatalogConflict = CatalogTestCategory("err-conflict")
+)
+
+func makeTestCatalog() *errcat.Catalog {
+	c := &errcat.Catalog{}
+	c.Add(ErrCatalogNotFound, errcat.CatalogEntry{
+		Description: "The item does not exist.",
+		HTTPStatus:  404,
+		DetailKeys:  []string{"item"},
+	})
+	c.Add(ErrCatalogConflict, errcat.CatalogEntry{
+		Description: "The item was changed concurrently.",
+		HTTPStatus:  409,
+		ExitCode:    3,
+	})
+	return c
+}
+
+func TestCatalog(t *testing.T) {
+	c := makeTestCatalog()
+	t.Run("entries are sorted and typed", func(t *testing.T) {
+		entries := c.Entries()
+		if len(entries) != 2 || entries[0].Category != "err-conflict" || entries[1].Category != "err-not-found" {
+			t.Fatalf("unexpected entries %v", entries)
+		}
+		if entries[0].Type != "github.com/warpfork/go-errcat_test.CatalogTestCategory" {
+			t.Errorf("unexpected type %q", entries[0].Type)
+		}
+	})
+	t.Run("adding again replaces", func(t *testing.T) {
+		c := makeTestCatalog()
+		c.Add(ErrCatalogConflict, errcat.CatalogEntry{Description: "new"})
+		if e, _ := c.Lookup(ErrCatalogConflict); e.Description != "new" || len(c.Entries()) != 2 {
+			t.Errorf("must replace -- got %v", c.Entries())
+		}
+	})
+	t.Run("lookup distinguishes types", func(t *testing.T) {
+		if _, ok := c.Lookup("err-conflict"); ok {
+			t.Errorf("plain string must not match typed entry")
+		}
+		if e, ok := c.Lookup(ErrCatalogConflict); !ok || e.HTTPStatus != 409 {
+			t.Errorf("must find typed entry -- got %v", e)
+		}
+	})
+	t.Run("json must match fixture", func(t *testing.T) {
+		bytes, err := json.Marshal(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect := `{"categories":[` +
+			`{"category":"err-conflict","type":"github.com/warpfork/go-errcat_test.CatalogTestCategory","description":"The item was changed concurrently.","http_status":409,"exit_code":3},` +
+			`{"category":"err-not-found","type":"github.com/warpfork/go-errcat_test.CatalogTestCategory","description":"The item does not exist.","http_status":404,"detail_keys":["item"]}` +
+			`]}`
+		if string(bytes) != expect {
+			t.Errorf("must match fixture -- got `%s`", string(bytes))
+		}
+		var c2 errcat.Catalog
+		if err := json.Unmarshal(bytes, &c2); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(c2.Entries(), c.Entries()) {
+			t.Errorf("must roundtrip -- got %v", c2.Entries())
+		}
+	})
+	t.Run("default catalog describes errcat's own categories", func(t *testing.T) {
+		if _, ok := errcat.DefaultCatalog.Lookup(errcat.ErrCategoryFilterRejection); !ok {
+			t.Errorf("must describe ErrCategoryFilterRejection")
+		}
+	})
+}
+
+func TestCatalogJSONSchema(t *testing.T) {
+	bytes, err := makeTestCatalog().JSONSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema struct {
+		Schema     string `json:"$schema"`
+		Properties struct {
+			Category struct {
+				Enum []string `json:"enum"`
+			} `json:"category"`
+			Cause struct {
+				Ref string `json:"$ref"`
+			} `json:"cause"`
+		} `json:"properties"`
+		Defs struct {
+			Cause struct {
+				Properties struct {
+					Category struct {
+						Enum []string `json:"enum"`
+					} `json:"category"`
+				} `json:"properties"`
+			} `json:"cause"`
+		} `json:"$defs"`
+		Required []string `json:"required"`
+	}
+	if err := json.Unmarshal(bytes, &schema); err != nil {
+		t.Fatal(err)
+	}
+	if schema.Schema != "https://json-schema.org/draft/2020-12/schema" {
+		t.Errorf("unexpected $schema %q", schema.Schema)
+	}
+	if !reflect.DeepEqual(schema.Properties.Category.Enum, []string{"err-conflict", "err-not-found"}) {
+		t.Errorf("unexpected enum %v", schema.Properties.Category.Enum)
+	}
+	if schema.Properties.Cause.Ref != "#/$defs/cause" || schema.Defs.Cause.Properties.Category.Enum != nil {
+		t.Errorf("causes must be allowed any category")
+	}
+	if !reflect.DeepEqual(schema.Required, []string{"category", "message"}) {
+		t.Errorf("unexpected required %v", schema.Required)
+	}
+	again, _ := makeTestCatalog().JSONSchema()
+	if string(again) != string(bytes) {
+		t.Errorf("must be deterministic")
+	}
+}
+
+func TestCatalogMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := makeTestCatalog().WriteMarkdown(&buf); err != nil {
+		t.Fatal(err)
+	}
+	expect := "# Error categories\n" +
+		"\n## `github.com/warpfork/go-errcat_test.CatalogTestCategory`\n" +
+		"\n### `err-conflict`\n\n" +
+		"The item was changed concurrently.\n\n" +
+		"- HTTP status: 409\n" +
+		"- Exit code: 3\n" +
+		"\n### `err-not-found`\n\n" +
+		"The item does not exist.\n\n" +
+		"- HTTP status: 404\n" +
+		"- Detail keys: `item`\n"
+	if buf.String() != expect {
+		t.Errorf("must match fixture -- got:\n%s", buf.String())
+	}
+}
+
+func TestDiffCatalogs(t *testing.T) {
+	entry := func(cat, name, typ string) errcat.CatalogEntry {
+		return errcat.CatalogEntry{Category: cat, Name: name, Type: typ}
+	}
+	before := &errcat.Catalog{}
+	before.AddEntry(entry("kept", "ErrKept", "p.Cat"))
+	before.AddEntry(entry("gone", "ErrGone", "p.Cat"))
+	before.AddEntry(entry("old-spelling", "ErrRenamed", "p.Cat"))
+	before.AddEntry(entry("moved", "ErrMoved", "p.Cat"))
+	after := &errcat.Catalog{}
+	after.AddEntry(errcat.CatalogEntry{Category: "kept", Name: "ErrKept", Type: "p.Cat", Description: "docs changed"})
+	after.AddEntry(entry("new-spelling", "ErrRenamed", "p.Cat"))
+	after.AddEntry(entry("moved", "ErrMoved", "q.Kind"))
+	after.AddEntry(entry("fresh", "ErrFresh", "p.Cat"))
+
+	d := errcat.DiffCatalogs(before, after)
+	expect := errcat.CatalogDiff{
+		Removed: []errcat.CatalogEntry{entry("gone", "ErrGone", "p.Cat")},
+		Renamed: []errcat.CatalogChange{{entry("old-spelling", "ErrRenamed", "p.Cat"), entry("new-spelling", "ErrRenamed", "p.Cat")}},
+		Retyped: []errcat.CatalogChange{{entry("moved", "ErrMoved", "p.Cat"), entry("moved", "ErrMoved", "q.Kind")}},
+		Added:   []errcat.CatalogEntry{entry("fresh", "ErrFresh", "p.Cat")},
+	}
+	if !reflect.DeepEqual(d, expect) {
+		t.Errorf("unexpected diff:\n%#v", d)
+	}
+	if !d.Breaking() {
+		t.Errorf("must be breaking")
+	}
+	if d := errcat.DiffCatalogs(before, before); d.Breaking() || len(d.Added) > 0 {
+		t.Errorf("identical catalogs must not differ -- got %#v", d)
+	}
+	if d := errcat.DiffCatalogs(&errcat.Catalog{}, after); d.Breaking() || len(d.Added) != 4 {
+		t.Errorf("additions alone must not be breaking -- got %#v", d)
+	}
+}
@@ -0,0 +1,264 @@
+package errcat
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+/*
+	A minimal CBOR (RFC 8949) codec: just enough to write the canonical errcat
+	object, and to read back anything a general CBOR encoder might have written
+	for one, without taking a dependency on a CBOR library.
+
+	The CBOR form is the same object as the json form (see `WireVersion`),
+	so decoding produces the same generic values `encoding/json` would (with
+	numbers as `json.Number`), and from there shares the json decoder's rules.
+*/
+
+const (
+	cborUint   = 0 << 5
+	cborNegint = 1 << 5
+	cborBytes  = 2 << 5
+	cborText   = 3 << 5
+	cborArray  = 4 << 5
+	cborMap    = 5 << 5
+	cborTag    = 6 << 5
+	cborSimple = 7 << 5
+
+	cborNull = cborSimple | 22
+
+	cborMaxDepth = 32
+)
+
+func appendCBORHead(buf []byte, major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return append(buf, major|byte(n))
+	case n <= math.MaxUint8:
+		return append(buf, major|24, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(buf, major|25), uint16(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(buf, major|26), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(buf, major|27), n)
+	}
+}
+
+func appendCBORText(buf []byte, s string) []byte {
+	return append(appendCBORHead(buf, cborText, uint64(len(s))), s...)
+}
+
+/*
+	Append the canonical object in deterministic encoding:
+	map keys sorted shortest first, then bytewise.
+*/
+func appendCBORErr(buf []byte, j jsonErr) []byte {
+	n := 2
+	for _, present := range []bool{j.Version > 0, j.Time != "", j.Cause != nil, len(j.Details) > 0} {
+		if present {
+			n++
+		}
+	}
+	buf = appendCBORHead(buf, cborMap, uint64(n))
+	if j.Version > 0 {
+		buf = appendCBORText(buf, "v")
+		buf = appendCBORHead(buf, cborUint, uint64(j.Version))
+	}
+	if j.Time != "" {
+		buf = appendCBORText(buf, "time")
+		buf = appendCBORText(buf, j.Time)
+	}
+	if j.Cause != nil {
+		buf = appendCBORText(buf, "cause")
+		buf = appendCBORErr(buf, *j.Cause)
+	}
+	if len(j.Details) > 0 {
+		keys := sortedDetailKeys(j.Details)
+		sort.SliceStable(keys, func(a, b int) bool { return len(keys[a]) < len(keys[b]) })
+		buf = appendCBORText(buf, "details")
+		buf = appendCBORHead(buf, cborMap, uint64(len(keys)))
+		for _, k := range keys {
+			buf = appendCBORText(buf, k)
+			buf = appendCBORText(buf, j.Details[k])
+		}
+	}
+	buf = appendCBORText(buf, "message")
+	buf = appendCBORText(buf, j.Message)
+	buf = appendCBORText(buf, "category")
+	buf = appendCBORText(buf, j.Category)
+	return buf
+}
+
+/*
+	Decode a CBOR errcat object, tolerantly, exactly as `ParseJSON` would
+	decode the same object in json.
+*/
+func parseCBOR(data []byte) (*errStruct, error) {
+	dec := cborDecoder{data: data}
+	v, err := dec.value(0)
+	if err == nil && dec.pos != len(data) {
+		err = fmt.Errorf("%d bytes of trailing data", len(data)-dec.pos)
+	}
+	if err != nil {
+		return nil, ErrorDetailed(ErrDecoding, "errcat: invalid cbor: "+err.Error(), map[string]string{"rule": "not-an-object"})
+	}
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, jsonRuleError("not-an-object", "", "errcat: invalid cbor: not a map")
+	}
+	if err := migrateJSONObject(obj); err != nil {
+		return nil, err
+	}
+	return errFromJSONObject(obj, false)
+}
+
+type cborDecoder struct {
+	data []byte
+	pos  int
+}
+
+// Read a head, returning its initial byte (major type and additional info) and argument.  Indefinite lengths aren't supported.
+func (dec *cborDecoder) head() (byte, uint64, error) {
+	if dec.pos >= len(dec.data) {
+		return 0, 0, fmt.Errorf("unexpected end of data")
+	}
+	b := dec.data[dec.pos]
+	dec.pos++
+	info := b & 0x1f
+	var size int
+	switch {
+	case info < 24:
+		return b, uint64(info), nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	default:
+		return 0, 0, fmt.Errorf("unsupported additional info %d at offset %d", info, dec.pos-1)
+	}
+	if len(dec.data)-dec.pos < size {
+		return 0, 0, fmt.Errorf("unexpected end of data")
+	}
+	var n uint64
+	for _, c := range dec.data[dec.pos : dec.pos+size] {
+		n = n<<8 | uint64(c)
+	}
+	dec.pos += size
+	return b, n, nil
+}
+
+func (dec *cborDecoder) bytes(n uint64) ([]byte, error) {
+	if n > uint64(len(dec.data)-dec.pos) {
+		return nil, fmt.Errorf("unexpected end of data")
+	}
+	bs := dec.data[dec.pos : dec.pos+int(n)]
+	dec.pos += int(n)
+	return bs, nil
+}
+
+func (dec *cborDecoder) value(depth int) (interface{}, error) {
+	if depth > cborMaxDepth {
+		return nil, fmt.Errorf("nested too deeply")
+	}
+	initial, n, err := dec.head()
+	if err != nil {
+		return nil, err
+	}
+	switch initial & 0xe0 {
+	case cborUint:
+		return json.Number(strconv.FormatUint(n, 10)), nil
+	case cborNegint:
+		if n == math.MaxUint64 {
+			return json.Number("-18446744073709551616"), nil
+		}
+		return json.Number("-" + strconv.FormatUint(n+1, 10)), nil
+	case cborBytes, cborText:
+		bs, err := dec.bytes(n)
+		return string(bs), err
+	case cborArray:
+		var arr []interface{}
+		for i := uint64(0); i < n; i++ {
+			v, err := dec.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		return arr, nil
+	case cborMap:
+		obj := map[string]interface{}{}
+		for i := uint64(0); i < n; i++ {
+			k, err := dec.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			ks, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("map key must be a string")
+			}
+			v, err := dec.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			obj[ks] = v
+		}
+		return obj, nil
+	case cborTag:
+		return dec.value(depth + 1) // tags are just hints; the tagged value stands on its own.
+	default: // cborSimple
+		return dec.simple(initial, n)
+	}
+}
+
+func (dec *cborDecoder) simple(b byte, n uint64) (interface{}, error) {
+	var f float64
+	switch {
+	case b == cborSimple|20:
+		return false, nil
+	case b == cborSimple|21:
+		return true, nil
+	case b == cborNull, b == cborSimple|23: // null and undefined
+		return nil, nil
+	case b == cborSimple|25:
+		f = halfFloat(uint16(n))
+	case b == cborSimple|26:
+		f = float64(math.Float32frombits(uint32(n)))
+	case b == cborSimple|27:
+		f = math.Float64frombits(n)
+	default:
+		return nil, fmt.Errorf("unsupported simple value %d", n)
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, fmt.Errorf("non-finite number")
+	}
+	return json.Number(strconv.FormatFloat(f, 'g', -1, 64)), nil
+}
+
+func halfFloat(h uint16) float64 {
+	exp, mant := int(h>>10)&0x1f, float64(h&0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 31:
+		f = math.Inf(1)
+		if mant != 0 {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		f = -f
+	}
+	return f
+}
@@ -0,0 +1,105 @@
+package errcat
+
+import (
+	"context"
+)
+
+/*
+	Return a context carrying the given details (as alternating keys and
+	values, like `WithDetailPairs`), in addition to any it already carries;
+	where a key is given again, the newer value wins.
+
+	Such details -- request IDs, tenants, operation names, the things you'd
+	otherwise have to add to errors at every layer -- are merged into errors
+	by `Enrich`, and by `New` with the `WithContext` option.
+*/
+func ContextWithDetails(ctx context.Context, kv ...string) context.Context {
+	if len(kv) == 0 {
+		return ctx
+	}
+	old := ContextDetails(ctx)
+	details := make(map[string]string, len(old)+len(kv)/2)
+	for k, v := range old {
+		details[k] = v
+	}
+	for i := 0; i < len(kv); i += 2 {
+		var v string
+		if i+1 < len(kv) {
+			v = kv[i+1]
+		}
+		details[kv[i]] = v
+	}
+	return context.WithValue(ctx, contextDetailsKey{}, details)
+}
+
+/*
+	Return the details carried by the context (see `ContextWithDetails`),
+	or nil if there are none.
+	The map must not be modified.
+*/
+func ContextDetails(ctx context.Context) map[string]string {
+	details, _ := ctx.Value(contextDetailsKey{}).(map[string]string)
+	return details
+}
+
+type contextDetailsKey struct{}
+
+/*
+	Return the error with the details carried by the context merged in.
+
+	Details the error already has take precedence: they were put there on
+	purpose, by code which knew more about the error than the context does.
+	So enriching is idempotent, and it's fine to enrich at every layer:
+
+		func (s *Server) Handle(ctx context.Context, req Request) (err error) {
+			ctx = errcat.ContextWithDetails(ctx, "request-id", req.ID)
+			defer func() { err = errcat.Enrich(ctx, err) }()
+			...
+		}
+
+	Nil errors are passed through, as are non-errcat errors (which have
+	nowhere to put details), and errors which already have all the details.
+	Otherwise, the result is a copy, just as with `AppendDetail`.
+*/
+func Enrich(ctx context.Context, err error) error {
+	err = denil(err, "Enrich")
+	e2, ok := err.(Error)
+	if !ok {
+		return err
+	}
+	extra := ContextDetails(ctx)
+	details := e2.Details()
+	missing := false
+	for k := range extra {
+		if _, ok := details[k]; !ok {
+			missing = true
+			break
+		}
+	}
+	if !missing {
+		return err
+	}
+	d2 := make(map[string]string, len(details)+len(extra))
+	for k, v := range extra {
+		d2[k] = v
+	}
+	for k, v := range details {
+		d2[k] = v
+	}
+	return rebase(e2, ErrorBase{e2.Category(), e2.Message(), d2})
+}
+
+/*
+	Add the details carried by the context (see `ContextWithDetails`).
+	As with `Enrich`, details given explicitly take precedence over the
+	context's, whichever order the options are in.
+*/
+func WithContext(ctx context.Context) Option {
+	return func(e *errStruct) {
+		for k, v := range ContextDetails(ctx) {
+			if _, ok := e.Details_[k]; !ok {
+				e.setDetail(k, v)
+			}
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package errcat_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/warpfork/go-errcat"
+)
+
+func TestContextDetails(t *testing.T) {
+	ctx := errcat.ContextWithDetails(context.Background(), "request-id", "r1", "tenant", "t1")
+	ctx = errcat.ContextWithDetails(ctx, "tenant", "t2", "op", "save")
+	t.Run("newer context details win", func(t *testing.T) {
+		expect := map[string]string{"request-id": "r1", "tenant": "t2", "op": "save"}
+		if got := errcat.ContextDetails(ctx); !reflect.DeepEqual(got, expect) {
+			t.Errorf("expected %v, got %v", expect, got)
+		}
+		if errcat.ContextDetails(context.Background()) != nil {
+			t.Errorf("plain contexts have no details")
+		}
+	})
+	t.Run("enrich", func(t *testing.T) {
+		orig := errcat.ErrorDetailed(ErrAsdf, "msg", map[string]string{"op": "explicit"})
+		err := errcat.Enrich(ctx, orig)
+		shouldCategory(t, err, ErrAsdf)
+		expect := map[string]string{"request-id": "r1", "tenant": "t2", "op": "explicit"}
+		if got := errcat.Details(err); !reflect.DeepEqual(got, expect) {
+			t.Errorf("expected %v, got %v", expect, got)
+		}
+		if !reflect.DeepEqual(errcat.Details(orig), map[string]string{"op": "explicit"}) {
+			t.Errorf("original must be untouched, got %v", errcat.Details(orig))
+		}
+		if again := errcat.Enrich(ctx, err); again != err {
+			t.Errorf("enriching again must be a no-op")
+		}
+	})
+	t.Run("enrich passes through what it can't enrich", func(t *testing.T) {
+		if errcat.Enrich(ctx, nil) != nil {
+			t.Errorf("nil must stay nil")
+		}
+		plain := fmt.Errorf("plain")
+		if errcat.Enrich(ctx, plain) != plain {
+			t.Errorf("non-errcat errors must pass through")
+		}
+		err := errcat.Errorf(ErrAsdf, "msg")
+		if errcat.Enrich(context.Background(), err) != err {
+			t.Errorf("errors must pass through contexts without details")
+		}
+	})
+	t.Run("enrich preserves custom types", func(t *testing.T) {
+		err := errcat.Enrich(ctx, &rateLimitedError{errcat.NewErrorBase(ErrAsdf, "slow down", nil), 5})
+		if e2, ok := err.(*rateLimitedError); !ok || e2.RetryAfter != 5 || e2.Details()["request-id"] != "r1" {
+			t.Errorf("got %#v", err)
+		}
+	})
+	t.Run("new with context, explicit details winning in any order", func(t *testing.T) {
+		for _, err := range []error{
+			errcat.New(ErrAsdf, "msg", errcat.WithContext(ctx), errcat.WithDetail("op", "explicit")),
+			errcat.New(ErrAsdf, "msg", errcat.WithDetail("op", "explicit"), errcat.WithContext(ctx)),
+		} {
+			expect := map[string]string{"request-id": "r1", "tenant": "t2", "op": "explicit"}
+			if got := errcat.Details(err); !reflect.DeepEqual(got, expect) {
+				t.Errorf("expected %v, got %v", expect, got)
+			}
+		}
+	})
+}
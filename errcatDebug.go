@@ -0,0 +1,7 @@
+//go:build errcat_debug
+// +build errcat_debug
+
+package errcat
+
+// Built with `-tags errcat_debug`: typed nils are checked for.  See `TypedNilReport`.
+const nilDebug = true
@@ -0,0 +1,95 @@
+package errcat
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+/*
+	Format implements `fmt.Formatter`, so printing an error shows as much
+	as you ask for:
+
+		%s, %v  -- the message, just like `Error()`.
+		%q      -- the message, quoted.
+		%+v     -- the message, then (indented, one per line) the category,
+		           time, details (sorted by key), annotation layers (see
+		           `PrefixAnnotate`), and captured frames (see `SetCapture`);
+		           then the cause, likewise, after "caused by: ".
+		%#v     -- a Go-syntax-like dump of everything, for debugging.
+
+	For example, with `%+v`:
+
+		saving: disk full
+			category: err-storage
+			details:
+				path: "/tmp/foo"
+			annotations:
+				saving
+			at main.save (/src/main.go:12)
+		caused by: no space left on device
+
+	Width and flags apply to `%s`, `%q`, and `%v` as they would to the message string.
+*/
+func (e *errStruct) Format(f fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && f.Flag('+'):
+		e.formatVerbose(f)
+	case verb == 'v' && f.Flag('#'):
+		e.formatGoSyntax(f)
+	case verb == 'v', verb == 's':
+		fmt.Fprintf(f, fmt.FormatString(f, 's'), e.Message_)
+	case verb == 'q':
+		fmt.Fprintf(f, fmt.FormatString(f, 'q'), e.Message_)
+	default:
+		fmt.Fprintf(f, "%%!%c(errcat.Error=%s)", verb, e.Message_)
+	}
+}
+
+func (e *errStruct) formatVerbose(w io.Writer) {
+	io.WriteString(w, e.Message_)
+	fmt.Fprintf(w, "\n\tcategory: %s", categoryString(e.Category_))
+	if !e.when().IsZero() {
+		fmt.Fprintf(w, "\n\ttime: %s", e.when().Format(time.RFC3339Nano))
+	}
+	if len(e.Details_) > 0 {
+		io.WriteString(w, "\n\tdetails:")
+		keys := sortedDetailKeys(e.Details_)
+		for _, k := range keys {
+			fmt.Fprintf(w, "\n\t\t%s: %q", k, e.Details_[k])
+		}
+	}
+	if len(e.annotations()) > 0 {
+		io.WriteString(w, "\n\tannotations:")
+		for _, a := range e.annotations() {
+			io.WriteString(w, "\n\t\t"+strings.Replace(a, "\n", "\n\t\t", -1))
+		}
+	}
+	for _, frame := range e.frames() {
+		fmt.Fprintf(w, "\n\tat %s", frame)
+	}
+	if e.cause() != nil {
+		fmt.Fprintf(w, "\ncaused by: %+v", e.cause())
+	}
+}
+
+func (e *errStruct) formatGoSyntax(w io.Writer) {
+	fmt.Fprintf(w, "&errcat.Error{Category:%#v, Message:%q", e.Category_, e.Message_)
+	if e.Details_ != nil {
+		fmt.Fprintf(w, ", Details:%#v", e.Details_)
+	}
+	if e.cause() != nil {
+		fmt.Fprintf(w, ", Cause:%#v", e.cause())
+	}
+	if !e.when().IsZero() {
+		fmt.Fprintf(w, ", Time:%q", e.when().Format(time.RFC3339Nano))
+	}
+	if e.annotations() != nil {
+		fmt.Fprintf(w, ", Annotations:%#v", e.annotations())
+	}
+	if len(e.frames()) > 0 {
+		fmt.Fprintf(w, ", Frames:%#v", e.frames())
+	}
+	io.WriteString(w, "}")
+}
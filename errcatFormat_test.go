@@ -0,0 +1,61 @@
+package errcat_test
+
+import (
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/warpfork/go-errcat"
+)
+
+func TestFormat(t *testing.T) {
+	when := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	inner := errcat.New(ErrorCategoryA("err-a"), "no space left", errcat.WithCause(io.EOF))
+	err := errcat.New(ErrAsdf, "disk full",
+		errcat.WithDetailPairs("path", "/tmp/foo", "attempt", "2"),
+		errcat.WithCause(inner),
+		errcat.WithTime(when),
+		errcat.WithAnnotation("writing foo"),
+	)
+	err = errcat.PrefixAnnotate(err, "saving {{.what}}", [][2]string{{"what", "bar"}})
+	plain := errcat.Errorf(ErrAsdf, "plain")
+	for _, tr := range []struct {
+		format string
+		err    error
+		expect string
+	}{
+		{"%v", plain, "plain"},
+		{"%s", plain, "plain"},
+		{"%q", plain, `"plain"`},
+		{"[%8s]", plain, "[   plain]"},
+		{"[%-8v]", plain, "[plain   ]"},
+		{"%d", plain, "%!d(errcat.Error=plain)"},
+		{"%+v", plain, "plain\n" +
+			"\tcategory: err-asdf"},
+		{"%#v", plain, `&errcat.Error{Category:"err-asdf", Message:"plain"}`},
+		{"%v", err, "saving bar: writing foo: disk full"},
+		{"%q", err, `"saving bar: writing foo: disk full"`},
+		{"%+v", err, "saving bar: writing foo: disk full\n" +
+			"\tcategory: err-asdf\n" +
+			"\ttime: 2026-01-02T03:04:05Z\n" +
+			"\tdetails:\n" +
+			"\t\tattempt: \"2\"\n" +
+			"\t\tpath: \"/tmp/foo\"\n" +
+			"\t\twhat: \"bar\"\n" +
+			"\tannotations:\n" +
+			"\t\tsaving bar\n" +
+			"\t\twriting foo\n" +
+			"caused by: no space left\n" +
+			"\tcategory: err-a\n" +
+			"caused by: EOF"},
+		{"%#v", err, `&errcat.Error{Category:"err-asdf", Message:"saving bar: writing foo: disk full", ` +
+			`Details:map[string]string{"attempt":"2", "path":"/tmp/foo", "what":"bar"}, ` +
+			`Cause:&errcat.Error{Category:"err-a", Message:"no space left", Cause:&errors.errorString{s:"EOF"}}, ` +
+			`Time:"2026-01-02T03:04:05Z", Annotations:[]string{"saving bar", "writing foo"}}`},
+	} {
+		if got := fmt.Sprintf(tr.format, tr.err); got != tr.expect {
+			t.Errorf("%s of %q: must match golden --\ngot:\n%s\nwant:\n%s", tr.format, tr.err.Error(), got, tr.expect)
+		}
+	}
+}
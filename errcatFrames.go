@@ -0,0 +1,68 @@
+package errcat
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+/*
+	Frame is a location in the source: where an error was made
+	(see `SetCapture` and `WithCaller`).
+*/
+type Frame struct {
+	Function string `json:"function"` // The package-qualified function name, e.g. "github.com/foo/bar.(*Thing).Do".
+	File     string `json:"file"`     // The full path of the source file.
+	Line     int    `json:"line"`
+}
+
+// String returns "function (file:line)", or just "file:line" if the function is unknown.
+func (f Frame) String() string {
+	if f.Function == "" {
+		return fmt.Sprintf("%s:%d", f.File, f.Line)
+	}
+	return fmt.Sprintf("%s (%s:%d)", f.Function, f.File, f.Line)
+}
+
+/*
+	Return the frames captured when the error was made, innermost first,
+	or nil if none were (or the error is nil, or isn't one of ours).
+*/
+func Frames(err error) []Frame {
+	if e, ok := denil(err, "Frames").(*errStruct); ok {
+		return e.frames()
+	}
+	return nil
+}
+
+/*
+	Return up to max frames of the current stack, innermost first, starting
+	from the first frame outside this package (so it's wherever our caller's
+	caller is, no matter how many of our own functions are in between).
+	Frames in `log/slog` are skipped too, so that what `SlogHandler` finds
+	is blamed on whoever called the logger.
+*/
+func captureFrames(max int) []Frame {
+	var pcs [64]uintptr
+	limit := max + 8 // leaving room for our own frames, which get skipped; walking the stack is the expensive part.
+	if limit > len(pcs) {
+		limit = len(pcs)
+	}
+	n := runtime.Callers(2, pcs[:limit])
+	frames := runtime.CallersFrames(pcs[:n])
+	var result []Frame
+	for len(result) < max {
+		frame, more := frames.Next()
+		if len(result) > 0 || !strings.HasPrefix(frame.Function, pkgFuncPrefix) && !strings.HasPrefix(frame.Function, "log/slog.") {
+			result = append(result, Frame{frame.Function, frame.File, frame.Line})
+		}
+		if !more {
+			break
+		}
+	}
+	return result
+}
+
+// Prefix of the qualified names of functions in this package (and only this package: the external test package is "errcat_test.").
+var pkgFuncPrefix = reflect.TypeOf(errStruct{}).PkgPath() + "."
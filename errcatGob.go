@@ -0,0 +1,90 @@
+package errcat
+
+import (
+	"bytes"
+	"encoding/gob"
+	"reflect"
+	"time"
+)
+
+/*
+	Register the concrete error type with gob, so that `error` and
+	`errcat.Error` interface fields holding errcat errors can be gob encoded
+	(and thus sent over net/rpc) without any further setup.
+
+	The registered name includes our package path (rather than the gob default
+	of "*errcat.errStruct"), so that two vendored copies of this package in
+	one binary don't collide.
+*/
+func init() {
+	gob.RegisterName("*"+reflect.TypeOf(errStruct{}).PkgPath()+".errStruct", &errStruct{})
+}
+
+/*
+	gobErr is the gob wire form of an errcat error.  Categories are sent as
+	their string form; causes are sent the same way, recursively, with
+	non-errcat causes sent as if they had the `unknown` category (as in json).
+
+	Cause and Time were added after the other fields; gob skips fields the
+	receiver doesn't know, so older decoders still read what they can.
+*/
+type gobErr struct {
+	Category string
+	Message  string
+	Details  map[string]string
+	Cause    *gobErr
+	Time     time.Time
+}
+
+func makeGobErr(err error) gobErr {
+	e, ok := err.(Error)
+	if !ok {
+		return gobErr{Category: categoryString(unknown), Message: err.Error()}
+	}
+	g := gobErr{Category: categoryString(e.Category()), Message: e.Message(), Details: e.Details()}
+	if e2, ok := e.(*errStruct); ok {
+		if e2.cause() != nil {
+			cause := makeGobErr(e2.cause())
+			g.Cause = &cause
+		}
+		g.Time = e2.when()
+	}
+	return g
+}
+
+func (g gobErr) decode() *errStruct {
+	e := decodedError(g.Category, g.Message, g.Details)
+	if g.Cause != nil {
+		e.ext().Cause_ = g.Cause.decode()
+	}
+	if !g.Time.IsZero() {
+		e.ext().Time_ = g.Time
+	}
+	return e
+}
+
+/*
+	GobEncode sends the category in its string form, since gob can't carry
+	arbitrary values in an `interface{}` field without every category type
+	being gob-registered.
+	The cause and time are sent too; captured frames are not.
+*/
+func (e *errStruct) GobEncode() ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(makeGobErr(e))
+	return buf.Bytes(), err
+}
+
+/*
+	GobDecode restores the category (and those of its causes) to its typed
+	value if it has been registered with `RegisterCategory`; otherwise it's
+	left as a plain string.
+*/
+func (e *errStruct) GobDecode(data []byte) error {
+	var g gobErr
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&g); err != nil {
+		return Errorf(ErrDecoding, "errcat: malformed gob: %s", err)
+	}
+	*e = *g.decode()
+	return nil
+}
@@ -0,0 +1,130 @@
+package errcat_test
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+	"testing"
+	"time"
+
+	"github.com/warpfork/go-errcat"
+)
+
+type GobTestCategory string
+
+const (
+	ErrGobRegistered   = GobTestCategory("err-gob-registered")
+	ErrGobUnregistered = GobTestCategory("err-gob-unregistered")
+)
+
+func init() {
+	errcat.RegisterCategory(ErrGobRegistered)
+}
+
+type gobEnvelope struct {
+	Name string
+	Err  error
+}
+
+func TestGob(t *testing.T) {
+	roundtrip := func(t *testing.T, e1 error) error {
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(gobEnvelope{"x", e1}); err != nil {
+			t.Fatal(err)
+		}
+		var env gobEnvelope
+		if err := gob.NewDecoder(&buf).Decode(&env); err != nil {
+			t.Fatal(err)
+		}
+		return env.Err
+	}
+	t.Run("registered categories roundtrip typed", func(t *testing.T) {
+		e2 := roundtrip(t, errcat.ErrorDetailed(ErrGobRegistered, "a msg", map[string]string{"deta": "il"}))
+		shouldCategory(t, e2, ErrGobRegistered)
+		if e2.Error() != "a msg" {
+			t.Errorf("message must roundtrip -- got %q", e2.Error())
+		}
+		if errcat.Details(e2)["deta"] != "il" {
+			t.Errorf("details must roundtrip -- got %v", errcat.Details(e2))
+		}
+	})
+	t.Run("unregistered categories roundtrip as strings", func(t *testing.T) {
+		e2 := roundtrip(t, errcat.Errorf(ErrGobUnregistered, "a msg"))
+		shouldCategory(t, e2, "err-gob-unregistered")
+	})
+	t.Run("causes and times roundtrip", func(t *testing.T) {
+		when := time.Date(2026, 1, 2, 3, 4, 5, 6, time.FixedZone("x", 3600))
+		e2 := roundtrip(t, errcat.New(ErrGobRegistered, "outer",
+			errcat.WithCause(errcat.New(ErrGobUnregistered, "middle", errcat.WithCause(errors.New("inner")))),
+			errcat.WithTime(when),
+		))
+		shouldCategory(t, e2, ErrGobRegistered)
+		if !errcat.Timestamp(e2).Equal(when) {
+			t.Errorf("time must roundtrip -- got %v", errcat.Timestamp(e2))
+		}
+		middle := errors.Unwrap(e2)
+		shouldCategory(t, middle, "err-gob-unregistered")
+		if middle == nil || middle.Error() != "middle" {
+			t.Fatalf("cause must roundtrip -- got %v", middle)
+		}
+		inner := errors.Unwrap(middle)
+		shouldCategory(t, inner, errcat.Category(errors.New("wild")))
+		if inner == nil || inner.Error() != "inner" {
+			t.Errorf("non-errcat causes must roundtrip as unknown -- got %v", inner)
+		}
+	})
+	t.Run("nil errors roundtrip as nil", func(t *testing.T) {
+		if e2 := roundtrip(t, nil); e2 != nil {
+			t.Errorf("must be nil -- got %v", e2)
+		}
+	})
+}
+
+type RPCTestService struct{}
+
+var rpcTestTime = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+type RPCTestReply struct {
+	Err error
+}
+
+func (RPCTestService) Fetch(key string, reply *RPCTestReply) error {
+	reply.Err = errcat.New(ErrGobRegistered, fmt.Sprintf("no such key %q", key),
+		errcat.WithDetail("key", key),
+		errcat.WithCause(errcat.Errorf(ErrGobRegistered, "shard down")),
+		errcat.WithTime(rpcTestTime),
+	)
+	return nil
+}
+
+func TestNetRPC(t *testing.T) {
+	srv := rpc.NewServer()
+	if err := srv.Register(RPCTestService{}); err != nil {
+		t.Fatal(err)
+	}
+	c1, c2 := net.Pipe()
+	go srv.ServeConn(c1)
+	client := rpc.NewClient(c2)
+	defer client.Close()
+
+	var reply RPCTestReply
+	if err := client.Call("RPCTestService.Fetch", "asdf", &reply); err != nil {
+		t.Fatal(err)
+	}
+	shouldCategory(t, reply.Err, ErrGobRegistered)
+	if reply.Err.Error() != `no such key "asdf"` {
+		t.Errorf("message must survive rpc -- got %q", reply.Err.Error())
+	}
+	if errcat.Details(reply.Err)["key"] != "asdf" {
+		t.Errorf("details must survive rpc -- got %v", errcat.Details(reply.Err))
+	}
+	if cause := errors.Unwrap(reply.Err); cause == nil || cause.Error() != "shard down" {
+		t.Errorf("cause must survive rpc -- got %v", cause)
+	}
+	if !errcat.Timestamp(reply.Err).Equal(rpcTestTime) {
+		t.Errorf("time must survive rpc -- got %v", errcat.Timestamp(reply.Err))
+	}
+}
@@ -0,0 +1,435 @@
+package errcat
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+)
+
+/*
+	GRPCCode is one of the canonical gRPC status code numbers
+	(as in the `google.rpc.Code` enum).
+
+	We declare our own copy of them (rather than importing grpc) so that
+	this package stays dependency-free: the numbers are part of the gRPC
+	wire protocol and are never going to change.
+*/
+type GRPCCode uint32
+
+const (
+	GRPCCodeOK                 GRPCCode = 0
+	GRPCCodeCanceled           GRPCCode = 1
+	GRPCCodeUnknown            GRPCCode = 2
+	GRPCCodeInvalidArgument    GRPCCode = 3
+	GRPCCodeDeadlineExceeded   GRPCCode = 4
+	GRPCCodeNotFound           GRPCCode = 5
+	GRPCCodeAlreadyExists      GRPCCode = 6
+	GRPCCodePermissionDenied   GRPCCode = 7
+	GRPCCodeResourceExhausted  GRPCCode = 8
+	GRPCCodeFailedPrecondition GRPCCode = 9
+	GRPCCodeAborted            GRPCCode = 10
+	GRPCCodeOutOfRange         GRPCCode = 11
+	GRPCCodeUnimplemented      GRPCCode = 12
+	GRPCCodeInternal           GRPCCode = 13
+	GRPCCodeUnavailable        GRPCCode = 14
+	GRPCCodeDataLoss           GRPCCode = 15
+	GRPCCodeUnauthenticated    GRPCCode = 16
+)
+
+var grpcCodeNames = [...]string{
+	"OK", "CANCELLED", "UNKNOWN", "INVALID_ARGUMENT", "DEADLINE_EXCEEDED",
+	"NOT_FOUND", "ALREADY_EXISTS", "PERMISSION_DENIED", "RESOURCE_EXHAUSTED",
+	"FAILED_PRECONDITION", "ABORTED", "OUT_OF_RANGE", "UNIMPLEMENTED",
+	"INTERNAL", "UNAVAILABLE", "DATA_LOSS", "UNAUTHENTICATED",
+}
+
+// String returns the name of the code as spelled in the `google.rpc.Code` enum.
+func (c GRPCCode) String() string {
+	if int(c) < len(grpcCodeNames) {
+		return grpcCodeNames[c]
+	}
+	return fmt.Sprintf("CODE(%d)", uint32(c))
+}
+
+//
+// The code table
+//    ...
+//
+
+var grpcCodes = struct {
+	sync.RWMutex
+	m map[interface{}]GRPCCode
+}{m: map[interface{}]GRPCCode{
+	ErrCategoryFilterRejection: GRPCCodeInternal,
+	ErrDecoding:                GRPCCodeInternal,
+}}
+
+/*
+	Declare which gRPC status code errors of the given category should map to.
+
+	Typically called from an `init` func in the package that declares the
+	category consts:
+
+		func init() {
+			errcat.RegisterGRPCCode(ErrNotFound, errcat.GRPCCodeNotFound)
+			errcat.RegisterGRPCCode(ErrDataCorruption, errcat.GRPCCodeDataLoss)
+		}
+
+	Registering the same category again replaces the earlier code.
+*/
+func RegisterGRPCCode(category interface{}, code GRPCCode) {
+	grpcCodes.Lock()
+	grpcCodes.m[category] = code
+	grpcCodes.Unlock()
+}
+
+/*
+	Return the gRPC status code for an error:
+	`GRPCCodeOK` if the error is nil,
+	the registered code if the error's category has one,
+	and `GRPCCodeUnknown` otherwise (including for non-errcat errors).
+*/
+func GRPCCodeOf(err error) GRPCCode {
+	switch cat := Category(err); cat {
+	case nil:
+		return GRPCCodeOK
+	case unknown:
+		return GRPCCodeUnknown
+	default:
+		grpcCodes.RLock()
+		code, ok := grpcCodes.m[cat]
+		grpcCodes.RUnlock()
+		if !ok {
+			return GRPCCodeUnknown
+		}
+		return code
+	}
+}
+
+//
+// The status value
+//    ...
+//
+
+/*
+	GRPCStatus is the errcat view of a `google.rpc.Status` message.
+
+	It marshals to (and unmarshals from) the exact protobuf wire bytes of a
+	`google.rpc.Status`, with the errcat category and details carried in a
+	single `google.rpc.ErrorInfo` entry of the status details
+	(category as the "reason", details as the "metadata" map).
+	A grpc-based service can plug this in with a few lines of glue,
+	without this package importing grpc:
+
+		func toStatus(err error) *status.Status {
+			var spb spb.Status
+			proto.Unmarshal(errcat.ToGRPCStatus(err).Marshal(), &spb)
+			return status.FromProto(&spb)
+		}
+*/
+type GRPCStatus struct {
+	Code     GRPCCode
+	Message  string
+	Category string            // Serialized category; empty if the status carried no ErrorInfo.
+	Details  map[string]string // Details of the error; carried as ErrorInfo metadata.
+}
+
+/*
+	GRPCStatusProvider is the shape of the `GRPCStatus()` method which grpc
+	looks for on errors -- except returning our own status type, since we
+	don't import grpc.
+
+	All errors produced by this package implement it.
+*/
+type GRPCStatusProvider interface {
+	GRPCStatus() *GRPCStatus
+}
+
+// GRPCErrorInfoDomain is the "domain" we set in the ErrorInfo detail we emit.
+const GRPCErrorInfoDomain = "errcat"
+
+const grpcErrorInfoTypeURL = "type.googleapis.com/google.rpc.ErrorInfo"
+
+func (e *errStruct) GRPCStatus() *GRPCStatus {
+	return &GRPCStatus{
+		Code:     GRPCCodeOf(e),
+		Message:  e.Message_,
+		Category: categoryString(e.Category_),
+		Details:  e.Details_,
+	}
+}
+
+/*
+	Return a GRPCStatus describing the error.
+
+	If the error already provides a `GRPCStatus()` method (as all errcat
+	errors do), that's used; other errors are described with
+	`GRPCCodeUnknown` and their message.
+
+	If the given error is nil, nil will be returned.
+*/
+func ToGRPCStatus(err error) *GRPCStatus {
+	switch e2 := denil(err, "ToGRPCStatus").(type) {
+	case nil:
+		return nil
+	case GRPCStatusProvider:
+		return e2.GRPCStatus()
+	case Error:
+		return &GRPCStatus{GRPCCodeOf(e2), e2.Message(), categoryString(e2.Category()), e2.Details()}
+	default:
+		return &GRPCStatus{GRPCCodeUnknown, e2.Error(), categoryString(unknown), nil}
+	}
+}
+
+/*
+	Return an errcat error for this status, or nil if the status code is OK.
+
+	If the status carried an errcat category, it's resolved through the
+	category registry (see `RegisterCategory`) and used as the new error's
+	category; if not, the status's `GRPCCode` becomes the category,
+	so you can still switch on it.
+*/
+func (s *GRPCStatus) Err() error {
+	if s == nil || s.Code == GRPCCodeOK {
+		return nil
+	}
+	if s.Category == "" {
+		return &errStruct{Category_: s.Code, Message_: s.Message, Details_: s.Details}
+	}
+	return decodedError(s.Category, s.Message, s.Details)
+}
+
+/*
+	Marshal the status as the protobuf wire bytes of a `google.rpc.Status`.
+
+	Output is deterministic: the ErrorInfo metadata entries are sorted by key.
+*/
+func (s *GRPCStatus) Marshal() []byte {
+	var buf []byte
+	if s.Code != 0 {
+		buf = appendProtoTag(buf, 1, protoWireVarint)
+		buf = appendProtoVarint(buf, uint64(s.Code))
+	}
+	if s.Message != "" {
+		buf = appendProtoBytes(buf, 2, []byte(s.Message))
+	}
+	if s.Category != "" || len(s.Details) > 0 {
+		var info []byte
+		info = appendProtoBytes(info, 1, []byte(s.Category))
+		info = appendProtoBytes(info, 2, []byte(GRPCErrorInfoDomain))
+		keys := sortedDetailKeys(s.Details)
+		for _, k := range keys {
+			var entry []byte
+			entry = appendProtoBytes(entry, 1, []byte(k))
+			entry = appendProtoBytes(entry, 2, []byte(s.Details[k]))
+			info = appendProtoBytes(info, 3, entry)
+		}
+		var anyMsg []byte
+		anyMsg = appendProtoBytes(anyMsg, 1, []byte(grpcErrorInfoTypeURL))
+		anyMsg = appendProtoBytes(anyMsg, 2, info)
+		buf = appendProtoBytes(buf, 3, anyMsg)
+	}
+	return buf
+}
+
+/*
+	Parse the protobuf wire bytes of a `google.rpc.Status`.
+
+	The category and details are taken from the first ErrorInfo in the status
+	details whose domain is `GRPCErrorInfoDomain`; other kinds of details
+	(and ErrorInfo from other domains) are skipped.
+	Malformed input returns an error of category `ErrDecoding`.
+*/
+func UnmarshalGRPCStatus(data []byte) (*GRPCStatus, error) {
+	s := &GRPCStatus{}
+	sawInfo := false
+	err := walkProtoFields(data, func(num int, wire int, v uint64, b []byte) error {
+		switch num {
+		case 1:
+			if err := checkProtoWire(num, wire, protoWireVarint); err != nil {
+				return err
+			}
+			s.Code = GRPCCode(uint32(v))
+		case 2:
+			if err := checkProtoWire(num, wire, protoWireBytes); err != nil {
+				return err
+			}
+			s.Message = string(b)
+		case 3:
+			if err := checkProtoWire(num, wire, protoWireBytes); err != nil {
+				return err
+			}
+			var typeURL string
+			var value []byte
+			err := walkProtoFields(b, func(num int, wire int, _ uint64, b []byte) error {
+				switch num {
+				case 1:
+					typeURL = string(b)
+				case 2:
+					value = b
+				default:
+					return nil
+				}
+				return checkProtoWire(num, wire, protoWireBytes)
+			})
+			if err != nil || typeURL != grpcErrorInfoTypeURL || sawInfo {
+				return err
+			}
+			var category, domain string
+			var details map[string]string
+			err = walkProtoFields(value, func(num int, wire int, _ uint64, b []byte) error {
+				if num < 1 || num > 3 {
+					return nil
+				}
+				if err := checkProtoWire(num, wire, protoWireBytes); err != nil {
+					return err
+				}
+				switch num {
+				case 1:
+					category = string(b)
+				case 2:
+					domain = string(b)
+				case 3:
+					var k, v string
+					err := walkProtoFields(b, func(num int, wire int, _ uint64, b []byte) error {
+						switch num {
+						case 1:
+							k = string(b)
+						case 2:
+							v = string(b)
+						default:
+							return nil
+						}
+						return checkProtoWire(num, wire, protoWireBytes)
+					})
+					if err != nil {
+						return err
+					}
+					if details == nil {
+						details = make(map[string]string)
+					}
+					details[k] = v
+				}
+				return nil
+			})
+			if err != nil || domain != GRPCErrorInfoDomain {
+				return err // ErrorInfo from some other service; its reason is no category of ours.
+			}
+			sawInfo = true
+			s.Category, s.Details = category, details
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, Errorf(ErrDecoding, "errcat: malformed grpc status: %s", err)
+	}
+	return s, nil
+}
+
+//
+// Protobuf wire format
+//    ...
+//
+
+// Just enough of the protobuf wire format to speak `google.rpc.Status`.
+
+const (
+	protoWireVarint = 0
+	protoWireI64    = 1
+	protoWireBytes  = 2
+	protoWireI32    = 5
+)
+
+func appendProtoVarint(buf []byte, v uint64) []byte {
+	for v >= 0x80 {
+		buf = append(buf, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(buf, byte(v))
+}
+
+func appendProtoTag(buf []byte, num int, wireType int) []byte {
+	return appendProtoVarint(buf, uint64(num)<<3|uint64(wireType))
+}
+
+func appendProtoBytes(buf []byte, num int, b []byte) []byte {
+	buf = appendProtoTag(buf, num, protoWireBytes)
+	buf = appendProtoVarint(buf, uint64(len(b)))
+	return append(buf, b...)
+}
+
+func readProtoVarint(data []byte) (uint64, int, error) {
+	var v uint64
+	for i := 0; i < len(data) && i < 10; i++ {
+		if i == 9 && data[i] > 1 {
+			return 0, 0, fmt.Errorf("varint overflows 64 bits")
+		}
+		v |= uint64(data[i]&0x7f) << (7 * uint(i))
+		if data[i] < 0x80 {
+			return v, i + 1, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("truncated varint")
+}
+
+/*
+	Call fn for each field in a protobuf message, with its wire type.
+	Varint and fixed-width fields are given as v; length-delimited fields as b.
+*/
+func walkProtoFields(data []byte, fn func(num int, wire int, v uint64, b []byte) error) error {
+	for len(data) > 0 {
+		tag, n, err := readProtoVarint(data)
+		if err != nil {
+			return err
+		}
+		data = data[n:]
+		num, wire := int(tag>>3), int(tag&7)
+		if num <= 0 {
+			return fmt.Errorf("invalid field number %d", num)
+		}
+		var v uint64
+		var b []byte
+		switch wire {
+		case protoWireVarint:
+			v, n, err = readProtoVarint(data)
+			if err != nil {
+				return err
+			}
+			data = data[n:]
+		case protoWireBytes:
+			l, n, err := readProtoVarint(data)
+			if err != nil {
+				return err
+			}
+			data = data[n:]
+			if l > uint64(len(data)) {
+				return fmt.Errorf("field %d: length %d exceeds remaining %d bytes", num, l, len(data))
+			}
+			b, data = data[:l], data[l:]
+		case protoWireI64:
+			if len(data) < 8 {
+				return fmt.Errorf("field %d: truncated fixed64", num)
+			}
+			v, data = binary.LittleEndian.Uint64(data), data[8:]
+		case protoWireI32:
+			if len(data) < 4 {
+				return fmt.Errorf("field %d: truncated fixed32", num)
+			}
+			v, data = uint64(binary.LittleEndian.Uint32(data)), data[4:]
+		default:
+			return fmt.Errorf("field %d: unsupported wire type %d", num, wire)
+		}
+		if err := fn(num, wire, v, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reject a field whose wire type isn't the one its schema says it has.
+func checkProtoWire(num int, wire int, want int) error {
+	if wire != want {
+		return fmt.Errorf("field %d: wire type %d, want %d", num, wire, want)
+	}
+	return nil
+}
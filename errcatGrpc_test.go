@@ -0,0 +1,120 @@
+package errcat_test
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/warpfork/go-errcat"
+)
+
+type GRPCTestCategory string
+
+const (
+	ErrGRPCMissing = GRPCTestCategory("err-grpc")
+	ErrGRPCOther   = GRPCTestCategory("err-grpc-other")
+)
+
+func init() {
+	errcat.RegisterGRPCCode(ErrGRPCMissing, errcat.GRPCCodeNotFound)
+}
+
+func TestGRPCCodeOf(t *testing.T) {
+	for _, tr := range []struct {
+		err  error
+		code errcat.GRPCCode
+	}{
+		{nil, errcat.GRPCCodeOK},
+		{errcat.Errorf(ErrGRPCMissing, "nope"), errcat.GRPCCodeNotFound},
+		{errcat.Errorf(ErrGRPCOther, "nope"), errcat.GRPCCodeUnknown},
+		{fmt.Errorf("wild"), errcat.GRPCCodeUnknown},
+		{errcat.Errorf(errcat.ErrCategoryFilterRejection, "bug"), errcat.GRPCCodeInternal},
+	} {
+		if code := errcat.GRPCCodeOf(tr.err); code != tr.code {
+			t.Errorf("code for %v: expected %s, got %s", tr.err, tr.code, code)
+		}
+	}
+}
+
+func TestGRPCStatus(t *testing.T) {
+	t.Run("status without errorinfo must match fixture", func(t *testing.T) {
+		s := &errcat.GRPCStatus{Code: errcat.GRPCCodeNotFound, Message: "boom"}
+		if bs := s.Marshal(); string(bs) != "\x08\x05\x12\x04boom" {
+			t.Errorf("must match fixture -- got %q", bs)
+		}
+	})
+	t.Run("status with errorinfo must match fixture", func(t *testing.T) {
+		err := errcat.ErrorDetailed(ErrGRPCMissing, "boom", map[string]string{"k": "v"})
+		bs := errcat.ToGRPCStatus(err).Marshal()
+		info := "\x0a\x08err-grpc" + "\x12\x06errcat" + "\x1a\x06\x0a\x01k\x12\x01v"
+		any := "\x0a\x28type.googleapis.com/google.rpc.ErrorInfo" + "\x12\x1a" + info
+		if string(bs) != "\x08\x05\x12\x04boom"+"\x1a\x46"+any {
+			t.Errorf("must match fixture -- got %q", bs)
+		}
+	})
+	t.Run("must roundtrip", func(t *testing.T) {
+		err := errcat.ErrorDetailed(ErrGRPCMissing, "boom", map[string]string{"k": "v", "a": "b"})
+		s, err2 := errcat.UnmarshalGRPCStatus(err.(errcat.GRPCStatusProvider).GRPCStatus().Marshal())
+		if err2 != nil {
+			t.Fatal(err2)
+		}
+		expect := &errcat.GRPCStatus{errcat.GRPCCodeNotFound, "boom", "err-grpc", map[string]string{"k": "v", "a": "b"}}
+		if !reflect.DeepEqual(s, expect) {
+			t.Errorf("must roundtrip -- got %#v", s)
+		}
+		if errcat.Category(s.Err()) != "err-grpc" || s.Err().Error() != "boom" {
+			t.Errorf("error must roundtrip -- got %v", s.Err())
+		}
+	})
+	t.Run("errorinfo from other domains is skipped", func(t *testing.T) {
+		field := func(tag byte, s string) string { return string([]byte{tag, byte(len(s))}) + s } // lengths under 128 only.
+		errorInfo := func(reason, domain string) string {
+			return field(0x1a, field(0x0a, "type.googleapis.com/google.rpc.ErrorInfo")+field(0x12, field(0x0a, reason)+field(0x12, domain)))
+		}
+		for _, tr := range []struct {
+			data   string
+			expect string
+		}{
+			{"\x08\x08" + errorInfo("RATE_LIMIT_EXCEEDED", "googleapis.com"), ""},
+			{"\x08\x08" + errorInfo("RATE_LIMIT_EXCEEDED", "googleapis.com") + errorInfo("err-grpc", "errcat"), "err-grpc"},
+		} {
+			s, err := errcat.UnmarshalGRPCStatus([]byte(tr.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.Category != tr.expect {
+				t.Errorf("expected category %q, got %q", tr.expect, s.Category)
+			}
+		}
+	})
+	t.Run("status without category uses the code as category", func(t *testing.T) {
+		s, err := errcat.UnmarshalGRPCStatus([]byte("\x08\x0e\x12\x04down"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		shouldCategory(t, s.Err(), errcat.GRPCCodeUnavailable)
+	})
+	t.Run("ok status is a nil error", func(t *testing.T) {
+		s, err := errcat.UnmarshalGRPCStatus(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.Err() != nil {
+			t.Errorf("must be nil -- got %v", s.Err())
+		}
+	})
+	t.Run("malformed input is rejected", func(t *testing.T) {
+		for _, data := range []string{
+			"\x08\x05\x12\x09boom",                      // truncated message.
+			"\x0a\x01\x05\x10\x07",                      // code as bytes, message as varint.
+			"\x08\x05\x10\x07",                          // message as varint.
+			"\x0d\x05\x00\x00\x00",                      // code as fixed32.
+			"\x08\x05\x1a\x02\x08\x01",                  // status detail as varint inside the Any.
+			"\x08" + strings.Repeat("\xff", 9) + "\x02", // code overflowing 64 bits.
+		} {
+			_, err := errcat.UnmarshalGRPCStatus([]byte(data))
+			shouldCategory(t, err, errcat.ErrDecoding)
+		}
+	})
+}
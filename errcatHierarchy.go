@@ -0,0 +1,110 @@
+package errcat
+
+import (
+	"fmt"
+	"sync"
+)
+
+/*
+	Categories may declare a parent category, forming a hierarchy, so that
+	handling code can match a whole family of categories at once with `Is`:
+
+		switch {
+		case errcat.Is(err, storage.ErrAny):
+			// ErrDiskFull, ErrCorruptBlock, ...
+		}
+
+	A category's parent comes from its `ParentCategory()` method if it has
+	one (see `ParentedCategory`), or else from `RegisterParent`.
+
+	The hierarchy is only a matter of matching: an error's category is still
+	the leaf value, and that's all that serialization ever writes.
+*/
+type ParentedCategory interface {
+	ParentCategory() interface{} // The parent category, or nil at the root.
+}
+
+var categoryParents = struct {
+	sync.RWMutex
+	m map[interface{}]interface{}
+}{m: map[interface{}]interface{}{}}
+
+/*
+	Declare the parent of a category.
+
+	Typically called from an `init` func in the package that declares the
+	category consts:
+
+		func init() {
+			errcat.RegisterParent(ErrDiskFull, ErrAny)
+			errcat.RegisterParent(ErrCorruptBlock, ErrAny)
+		}
+
+	Panics if the registration would make a category its own ancestor.
+	Registering the same category again replaces its parent.
+*/
+func RegisterParent(child, parent interface{}) {
+	categoryParents.Lock()
+	defer categoryParents.Unlock()
+	var seen []interface{}
+	for c := parent; c != nil; c = parentOfLocked(c) {
+		if c == child {
+			panic(fmt.Sprintf("errcat: registering %v as parent of %v would make a cycle", parent, child))
+		}
+		if containsCategory(seen, c) {
+			break // a pre-existing cycle among ParentCategory methods; not this registration's fault.
+		}
+		seen = append(seen, c)
+	}
+	categoryParents.m[child] = parent
+}
+
+/*
+	Return the parent of a category, or nil if it has none.
+*/
+func ParentOf(category interface{}) interface{} {
+	categoryParents.RLock()
+	defer categoryParents.RUnlock()
+	return parentOfLocked(category)
+}
+
+func parentOfLocked(category interface{}) interface{} {
+	if p, ok := category.(ParentedCategory); ok {
+		return p.ParentCategory()
+	}
+	return categoryParents.m[category]
+}
+
+/*
+	Return true if the error's category is the given category,
+	or a descendant of it.
+
+	Nil errors match nothing (not even a nil category; use `Category(err) == nil`
+	for that).
+	Cycles among `ParentCategory` methods are detected, and end the search.
+*/
+func Is(err error, category interface{}) bool {
+	if err = denil(err, "Is"); err == nil {
+		return false
+	}
+	var seen []interface{}
+	for c := Category(err); c != nil; c = ParentOf(c) {
+		if c == category {
+			return true
+		}
+		if containsCategory(seen, c) {
+			return false
+		}
+		seen = append(seen, c)
+	}
+	return false
+}
+
+func containsCategory(categories []interface{}, category interface{}) bool {
+	for _, c := range categories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
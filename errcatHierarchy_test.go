@@ -0,0 +1,97 @@
+package errcat_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/warpfork/go-errcat"
+)
+
+type StorageCategory string
+
+const (
+	ErrStorage      = StorageCategory("storage")
+	ErrStorageDisk  = StorageCategory("storage-disk")
+	ErrDiskFull     = StorageCategory("storage-disk-full")
+	ErrCorruptBlock = StorageCategory("storage-corrupt-block")
+)
+
+func init() {
+	errcat.RegisterParent(ErrStorageDisk, ErrStorage)
+	errcat.RegisterParent(ErrDiskFull, ErrStorageDisk)
+	errcat.RegisterParent(ErrCorruptBlock, ErrStorage)
+}
+
+// CyclicCategory declares parents with a method, and does so badly.
+type CyclicCategory string
+
+func (c CyclicCategory) ParentCategory() interface{} {
+	switch c {
+	case "a":
+		return CyclicCategory("b")
+	case "b":
+		return CyclicCategory("a")
+	default:
+		return nil
+	}
+}
+
+func TestIs(t *testing.T) {
+	for _, tr := range []struct {
+		err      error
+		category interface{}
+		expect   bool
+	}{
+		{errcat.Errorf(ErrDiskFull, "full"), ErrDiskFull, true},
+		{errcat.Errorf(ErrDiskFull, "full"), ErrStorageDisk, true},
+		{errcat.Errorf(ErrDiskFull, "full"), ErrStorage, true},
+		{errcat.Errorf(ErrCorruptBlock, "bad"), ErrStorage, true},
+		{errcat.Errorf(ErrCorruptBlock, "bad"), ErrStorageDisk, false},
+		{errcat.Errorf(ErrStorage, "eh"), ErrDiskFull, false},
+		{errcat.Errorf("storage-disk-full", "untyped"), ErrStorage, false},
+		{errcat.Errorf(CyclicCategory("a"), "loop"), CyclicCategory("b"), true},
+		{errcat.Errorf(CyclicCategory("a"), "loop"), ErrStorage, false},
+		{fmt.Errorf("wild"), ErrStorage, false},
+		{nil, ErrStorage, false},
+		{nil, nil, false},
+	} {
+		if got := errcat.Is(tr.err, tr.category); got != tr.expect {
+			t.Errorf("Is(%v, %v): expected %v, got %v", tr.err, tr.category, tr.expect, got)
+		}
+	}
+}
+
+func TestRegisterParent(t *testing.T) {
+	t.Run("cycles are rejected", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("must panic")
+			}
+		}()
+		errcat.RegisterParent(ErrStorage, ErrDiskFull)
+	})
+	t.Run("self-parenting is rejected", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("must panic")
+			}
+		}()
+		errcat.RegisterParent(StorageCategory("solo"), StorageCategory("solo"))
+	})
+	t.Run("rejected registrations leave the hierarchy alone", func(t *testing.T) {
+		if errcat.ParentOf(ErrStorage) != nil {
+			t.Errorf("root must stay a root -- got parent %v", errcat.ParentOf(ErrStorage))
+		}
+	})
+}
+
+func TestHierarchySerialization(t *testing.T) {
+	bytes, err := json.Marshal(errcat.Errorf(ErrDiskFull, "full"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(bytes) != `{"category":"storage-disk-full","message":"full"}` {
+		t.Errorf("must write only the leaf -- got `%s`", bytes)
+	}
+}
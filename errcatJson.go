@@ -0,0 +1,299 @@
+package errcat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+/*
+	Parse an errcat error from its json form.
+
+	This decoder is tolerant, because errcat json gets written by all sorts
+	of things (including people following older docs by hand):
+
+		- the message may be under either "message" or "msg" (if both are present, "message" wins);
+		- unknown fields are ignored;
+		- detail values which aren't strings are stringified
+		  (numbers and bools as written, null as empty, objects and arrays as compact json);
+		- a category or message which isn't a string is stringified the same way;
+		- a missing, null, or empty category becomes `ErrMissingCategory`;
+		- a "time" which can't be parsed is ignored;
+		- anything after the object is ignored.
+
+	The category is restored to its typed value if it has been registered
+	with `RegisterCategory`; otherwise it's left as a plain string.
+
+	Every version of the wire format is understood (see `WireVersion`).
+
+	Input which isn't a json object (or has a "details" or "cause" which isn't one,
+	or has a version marker we don't understand) returns an error of
+	category `ErrDecoding`.
+	Use `ParseJSONStrict` to reject anything that isn't exactly the
+	canonical form.
+*/
+func ParseJSON(data []byte) (Error, error) {
+	e, err := parseJSON(data, false)
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+/*
+	Identical to `ParseJSON`, but rejects anything other than exactly the
+	canonical form this package emits.
+
+	The returned error has category `ErrDecoding`, and a "rule" detail
+	saying exactly which rule was violated (plus a "field" detail, where
+	there's a field to blame):
+
+		- "not-an-object"        -- the input isn't a json object at all;
+		- "trailing-data"        -- there's more input after the object;
+		- "invalid-version"      -- the "v" marker isn't a positive integer;
+		- "unsupported-version"  -- the "v" marker is newer than `WireVersion`;
+		- "unknown-field"        -- a field other than "category", "message", "details", "cause", or "time";
+		- "legacy-msg-key"       -- the message was under "msg" rather than "message";
+		- "non-string-category"  -- the category isn't a json string;
+		- "non-string-message"   -- the message isn't a json string;
+		- "details-not-object"   -- "details" isn't a json object (null included);
+		- "non-string-detail"    -- a detail value isn't a json string;
+		- "cause-not-object"     -- "cause" isn't a json object;
+		- "invalid-time"         -- "time" isn't an RFC 3339 timestamp string;
+		- "missing-category"     -- the category is missing, null, or empty;
+		- "missing-message"      -- there's no "message" field.
+
+	Fields (and details) are checked in sorted order, so the rule reported
+	for input which breaks several of them is deterministic.
+	The cause is checked by the same rules; fields blamed within it are
+	prefixed with "cause.".
+*/
+func ParseJSONStrict(data []byte) (Error, error) {
+	e, err := parseJSON(data, true)
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+/*
+	MarshalJSON writes the canonical form; see `WireVersion`.
+*/
+func (e *errStruct) MarshalJSON() ([]byte, error) {
+	return json.Marshal(toJSONErr(e))
+}
+
+/*
+	UnmarshalJSON decodes tolerantly, exactly like `ParseJSON`.
+*/
+func (e *errStruct) UnmarshalJSON(data []byte) error {
+	e2, err := parseJSON(data, false)
+	if err != nil {
+		return err
+	}
+	*e = *e2
+	return nil
+}
+
+func parseJSON(data []byte, strict bool) (*errStruct, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var obj map[string]interface{}
+	if err := dec.Decode(&obj); err != nil || obj == nil {
+		return nil, jsonRuleError("not-an-object", "", "errcat: invalid json: not an object")
+	}
+	if strict {
+		if _, err := dec.Token(); err != io.EOF {
+			return nil, jsonRuleError("trailing-data", "", "errcat: invalid json: unexpected data after the object")
+		}
+	}
+	if err := migrateJSONObject(obj); err != nil {
+		return nil, err
+	}
+	return errFromJSONObject(obj, strict)
+}
+
+func errFromJSONObject(obj map[string]interface{}, strict bool) (*errStruct, error) {
+	var category, msg string
+	var details map[string]string
+	var cause error
+	var when time.Time
+	for _, k := range sortedObjectKeys(obj) {
+		switch v := obj[k]; k {
+		case "category":
+			s, ok := v.(string)
+			if !ok && v != nil {
+				if strict {
+					return nil, jsonRuleError("non-string-category", k, "errcat: invalid json: category must be a string")
+				}
+				s = jsonStringify(v)
+			}
+			category = s
+		case "message", "msg":
+			if k == "msg" {
+				if strict {
+					return nil, jsonRuleError("legacy-msg-key", k, "errcat: invalid json: message must be under \"message\", not \"msg\"")
+				}
+				if _, ok := obj["message"]; ok {
+					continue
+				}
+			}
+			s, ok := v.(string)
+			if !ok {
+				if strict {
+					return nil, jsonRuleError("non-string-message", k, "errcat: invalid json: message must be a string")
+				}
+				s = jsonStringify(v)
+			}
+			msg = s
+		case "details":
+			switch d := v.(type) {
+			case nil:
+				if strict {
+					return nil, jsonRuleError("details-not-object", k, "errcat: invalid json: details must be an object, not null")
+				}
+			case map[string]interface{}:
+				details = make(map[string]string, len(d))
+				for _, dk := range sortedObjectKeys(d) {
+					dv := d[dk]
+					s, ok := dv.(string)
+					if !ok {
+						if strict {
+							return nil, jsonRuleError("non-string-detail", "details."+dk, "errcat: invalid json: detail %q must be a string", dk)
+						}
+						s = jsonStringify(dv)
+					}
+					details[dk] = s
+				}
+			default:
+				return nil, jsonRuleError("details-not-object", k, "errcat: invalid json: details must be an object")
+			}
+		case "cause":
+			switch c := v.(type) {
+			case nil:
+			case map[string]interface{}:
+				e, err := errFromJSONObject(c, strict)
+				if err != nil {
+					if d := Details(err); d["field"] != "" {
+						d["field"] = "cause." + d["field"]
+					}
+					return nil, err
+				}
+				cause = e
+			default:
+				return nil, jsonRuleError("cause-not-object", k, "errcat: invalid json: cause must be an object")
+			}
+		case "time":
+			s, _ := v.(string)
+			t, err := time.Parse(time.RFC3339Nano, s)
+			if err != nil && strict {
+				return nil, jsonRuleError("invalid-time", k, "errcat: invalid json: time must be an RFC 3339 timestamp")
+			}
+			when = t
+		default:
+			if strict {
+				return nil, jsonRuleError("unknown-field", k, "errcat: invalid json: unknown field %q", k)
+			}
+		}
+	}
+	if strict {
+		if category == "" {
+			return nil, jsonRuleError("missing-category", "category", "errcat: invalid json: category is missing")
+		}
+		if _, ok := obj["message"]; !ok {
+			return nil, jsonRuleError("missing-message", "message", "errcat: invalid json: message is missing")
+		}
+	}
+	e := decodedError(category, msg, details)
+	if cause != nil || !when.IsZero() {
+		x := e.ext()
+		x.Cause_, x.Time_ = cause, when
+	}
+	return e, nil
+}
+
+// Return the keys of a decoded json object, sorted, so its fields are checked in a deterministic order.
+func sortedObjectKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func jsonRuleError(rule string, field string, format string, args ...interface{}) error {
+	details := map[string]string{"rule": rule}
+	if field != "" {
+		details["field"] = field
+	}
+	return ErrorDetailed(ErrDecoding, fmt.Sprintf(format, args...), details)
+}
+
+// Render a decoded json value as a string: strings as themselves, null as empty, anything else as compact json.
+func jsonStringify(v interface{}) string {
+	switch v2 := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v2
+	case json.Number:
+		return v2.String()
+	default:
+		bs, _ := json.Marshal(v2)
+		return string(bs)
+	}
+}
+
+// jsonErr is the canonical json form of an errcat error.
+type jsonErr struct {
+	Version  int               `json:"v,omitempty"`
+	Category string            `json:"category"`
+	Message  string            `json:"message"`
+	Details  map[string]string `json:"details,omitempty"`
+	Cause    *jsonErr          `json:"cause,omitempty"`
+	Time     string            `json:"time,omitempty"`
+	Frames   []Frame           `json:"frames,omitempty"` // Only in the debug form; see `DebugJSON`.
+}
+
+/*
+	Describe any error in the canonical json form (with a version marker,
+	if it needs one).
+	Non-errcat errors get the `unknown` category and their `Error()` text.
+*/
+func toJSONErr(err error) jsonErr {
+	return makeJSONErr(err, false)
+}
+
+// Identical to `toJSONErr`, but including captured frames.
+func toDebugJSONErr(err error) jsonErr {
+	return makeJSONErr(err, true)
+}
+
+func makeJSONErr(err error, debug bool) jsonErr {
+	e2, ok := err.(Error)
+	if !ok {
+		return jsonErr{Category: categoryString(unknown), Message: err.Error()}
+	}
+	j := jsonErr{Category: categoryString(e2.Category()), Message: e2.Message(), Details: e2.Details()}
+	if e3, ok := e2.(*errStruct); ok {
+		if v := wireVersionOf(e3); v > 1 {
+			j.Version = v
+		}
+		if e3.cause() != nil {
+			cause := makeJSONErr(e3.cause(), debug)
+			cause.Version = 0 // the outermost marker covers the whole thing.
+			j.Cause = &cause
+		}
+		if !e3.when().IsZero() {
+			j.Time = e3.when().Format(time.RFC3339Nano)
+		}
+		if debug {
+			j.Frames = e3.frames()
+		}
+	}
+	return j
+}
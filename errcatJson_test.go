@@ -0,0 +1,110 @@
+package errcat_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/warpfork/go-errcat"
+)
+
+func TestParseJSON(t *testing.T) {
+	for _, tr := range []struct {
+		title    string
+		json     string
+		category interface{}
+		message  string
+		details  map[string]string
+		rule     string // expected violation when strict; empty if strict should accept it too.
+	}{
+		{"canonical form",
+			`{"category":"err-xml","message":"hi","details":{"k":"v"}}`,
+			ErrXMLRegistered, "hi", map[string]string{"k": "v"}, ""},
+		{"documented msg key",
+			`{"category":"err-xml","msg":"hi"}`,
+			ErrXMLRegistered, "hi", nil, "legacy-msg-key"},
+		{"message wins over msg",
+			`{"category":"err-xml","msg":"old","message":"hi"}`,
+			ErrXMLRegistered, "hi", nil, "legacy-msg-key"},
+		{"unknown fields",
+			`{"category":"err-xml","message":"hi","when":12}`,
+			ErrXMLRegistered, "hi", nil, "unknown-field"},
+		{"non-string details",
+			`{"category":"err-xml","message":"hi","details":{"n":12.50,"b":true,"z":null,"o":{"a":[1,2]}}}`,
+			ErrXMLRegistered, "hi", map[string]string{"n": "12.50", "b": "true", "z": "", "o": `{"a":[1,2]}`}, "non-string-detail"},
+		{"non-string category",
+			`{"category":404,"message":"hi"}`,
+			"404", "hi", nil, "non-string-category"},
+		{"missing category",
+			`{"message":"hi"}`,
+			errcat.ErrMissingCategory, "hi", nil, "missing-category"},
+		{"null category",
+			`{"category":null,"message":"hi"}`,
+			errcat.ErrMissingCategory, "hi", nil, "missing-category"},
+		{"missing message",
+			`{"category":"err-xml"}`,
+			ErrXMLRegistered, "", nil, "missing-message"},
+		{"null details",
+			`{"category":"err-xml","message":"hi","details":null}`,
+			ErrXMLRegistered, "hi", nil, "details-not-object"},
+		{"trailing data",
+			`{"category":"err-xml","message":"hi"} {"x":1}`,
+			ErrXMLRegistered, "hi", nil, "trailing-data"},
+		{"trailing whitespace",
+			"{\"category\":\"err-xml\",\"message\":\"hi\"}\n\t ",
+			ErrXMLRegistered, "hi", nil, ""},
+	} {
+		t.Run(tr.title, func(t *testing.T) {
+			e2, err := errcat.ParseJSON([]byte(tr.json))
+			if err != nil {
+				t.Fatal(err)
+			}
+			shouldCategory(t, e2, tr.category)
+			if e2.Message() != tr.message {
+				t.Errorf("expected message %q, got %q", tr.message, e2.Message())
+			}
+			if !reflect.DeepEqual(e2.Details(), tr.details) {
+				t.Errorf("expected details %v, got %v", tr.details, e2.Details())
+			}
+			_, err = errcat.ParseJSONStrict([]byte(tr.json))
+			switch {
+			case tr.rule == "" && err != nil:
+				t.Errorf("strict mode should accept, got %v", err)
+			case tr.rule != "":
+				shouldCategory(t, err, errcat.ErrDecoding)
+				if rule := errcat.Details(err)["rule"]; rule != tr.rule {
+					t.Errorf("strict mode should report rule %q, got %q (%v)", tr.rule, rule, err)
+				}
+			}
+		})
+	}
+	t.Run("non-objects are rejected", func(t *testing.T) {
+		for _, s := range []string{``, `[]`, `"str"`, `null`, `{`} {
+			_, err := errcat.ParseJSON([]byte(s))
+			shouldCategory(t, err, errcat.ErrDecoding)
+		}
+	})
+	t.Run("non-object details are rejected", func(t *testing.T) {
+		_, err := errcat.ParseJSON([]byte(`{"category":"x","details":"str"}`))
+		shouldCategory(t, err, errcat.ErrDecoding)
+	})
+	t.Run("the first bad detail in sorted order is blamed", func(t *testing.T) {
+		for i := 0; i < 20; i++ {
+			_, err := errcat.ParseJSONStrict([]byte(`{"category":"err-xml","details":{"m":1,"c":2,"x":3,"a":4,"q":5}}`))
+			if field := errcat.Details(err)["field"]; field != "details.a" {
+				t.Fatalf("blamed field %q, want %q", field, "details.a")
+			}
+		}
+	})
+	t.Run("must roundtrip with encoding/json", func(t *testing.T) {
+		bytes, err := json.Marshal(errcat.ErrorDetailed(ErrXMLRegistered, "hi", map[string]string{"k": "v"}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		e2, err := errcat.ParseJSONStrict(bytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		shouldCategory(t, e2, ErrXMLRegistered)
+	})
+}
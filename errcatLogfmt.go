@@ -0,0 +1,163 @@
+package errcat
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+/*
+	Render an error as a single logfmt line:
+
+		category=your_tag msg="full text goes here" detail.foo=bar
+
+	Keys are always in this order, with details sorted by key, so lines are
+	stable and greppable.
+	Values are quoted (Go-style, with escapes) whenever they're empty or
+	contain spaces, quotes, '=' or anything unprintable, so the line never
+	breaks; detail keys which couldn't stand bare are quoted after the
+	"detail." prefix.
+
+	Non-errcat errors are rendered with the `unknown` category.
+	A nil error renders as an empty string.
+*/
+func FormatLogfmt(err error) string {
+	return string(AppendLogfmt(nil, err))
+}
+
+/*
+	Identical to `FormatLogfmt`, but appends to a buffer, for use when
+	assembling larger log lines.
+*/
+func AppendLogfmt(buf []byte, err error) []byte {
+	if err = denil(err, "AppendLogfmt"); err == nil {
+		return buf
+	}
+	buf = append(buf, "category="...)
+	buf = appendLogfmtValue(buf, categoryString(Category(err)))
+	msg := err.Error()
+	if e2, ok := err.(Error); ok {
+		msg = e2.Message()
+	}
+	buf = append(buf, " msg="...)
+	buf = appendLogfmtValue(buf, msg)
+	details := Details(err)
+	keys := sortedDetailKeys(details)
+	for _, k := range keys {
+		buf = append(buf, " detail."...)
+		buf = appendLogfmtValue(buf, k)
+		buf = append(buf, '=')
+		buf = appendLogfmtValue(buf, details[k])
+	}
+	return buf
+}
+
+func appendLogfmtValue(buf []byte, s string) []byte {
+	if logfmtNeedsQuote(s) {
+		return strconv.AppendQuote(buf, s)
+	}
+	return append(buf, s...)
+}
+
+func logfmtNeedsQuote(s string) bool {
+	if s == "" {
+		return true
+	}
+	for _, r := range s {
+		if r == '=' || r == '"' || r == '\\' || unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			return true
+		}
+	}
+	return !utf8.ValidString(s)
+}
+
+/*
+	Parse an errcat error back out of a logfmt line (as produced by
+	`FormatLogfmt`).
+
+	Keys other than "category", "msg", and "detail.*" are ignored, so it's
+	fine to hand this a whole log line with timestamps and levels and
+	whatever else in it.
+	The category is restored to its typed value if it has been registered
+	with `RegisterCategory`.
+
+	Malformed lines, and lines with no category at all, return an error of
+	category `ErrDecoding`.
+*/
+func ParseLogfmt(line string) (Error, error) {
+	var category, msg string
+	var details map[string]string
+	sawCategory := false
+	for i := 0; ; {
+		for i < len(line) && line[i] == ' ' {
+			i++
+		}
+		if i >= len(line) {
+			break
+		}
+		start := i
+		var key string
+		var err error
+		if strings.HasPrefix(line[i:], "detail.\"") {
+			key, i, err = scanLogfmtQuoted(line, i+len("detail."))
+			if err != nil {
+				return nil, Errorf(ErrDecoding, "errcat: malformed logfmt at offset %d: %s", start, err)
+			}
+			key = "detail." + key
+		} else {
+			for i < len(line) && line[i] != '=' && line[i] != ' ' {
+				i++
+			}
+			key = line[start:i]
+		}
+		var value string
+		if i < len(line) && line[i] == '=' {
+			i++
+			if i < len(line) && line[i] == '"' {
+				value, i, err = scanLogfmtQuoted(line, i)
+				if err != nil {
+					return nil, Errorf(ErrDecoding, "errcat: malformed logfmt at offset %d: %s", start, err)
+				}
+			} else {
+				vstart := i
+				for i < len(line) && line[i] != ' ' {
+					i++
+				}
+				value = line[vstart:i]
+			}
+		}
+		switch {
+		case key == "category":
+			category, sawCategory = value, true
+		case key == "msg":
+			msg = value
+		case strings.HasPrefix(key, "detail."):
+			if details == nil {
+				details = make(map[string]string)
+			}
+			details[key[len("detail."):]] = value
+		}
+	}
+	if !sawCategory {
+		return nil, Errorf(ErrDecoding, "errcat: logfmt line has no category")
+	}
+	return decodedError(category, msg, details), nil
+}
+
+// Unquote the Go-quoted string starting at line[i]; return it and the index just past it.
+func scanLogfmtQuoted(line string, i int) (string, int, error) {
+	j := i + 1
+	for ; j < len(line); j++ {
+		if line[j] == '\\' {
+			j++
+		} else if line[j] == '"' {
+			break
+		}
+	}
+	if j >= len(line) {
+		return "", 0, strconv.ErrSyntax
+	}
+	s, err := strconv.Unquote(line[i : j+1])
+	return s, j + 1, err
+}
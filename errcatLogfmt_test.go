@@ -0,0 +1,85 @@
+package errcat_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/warpfork/go-errcat"
+)
+
+func TestLogfmt(t *testing.T) {
+	for _, tr := range []struct {
+		title string
+		err   error
+		line  string
+	}{
+		{"simple",
+			errcat.Errorf(ErrAsdf, "asdf"),
+			`category=err-asdf msg=asdf`},
+		{"quoting",
+			errcat.Errorf(ErrAsdf, "asdf: \"fmtme\" = 1\n"),
+			`category=err-asdf msg="asdf: \"fmtme\" = 1\n"`},
+		{"empty message",
+			errcat.Errorf(ErrAsdf, ""),
+			`category=err-asdf msg=""`},
+		{"details sorted",
+			errcat.ErrorDetailed(ErrAsdf, "a msg", map[string]string{"zed": "z", "alpha": "a b", "mid": ""}),
+			`category=err-asdf msg="a msg" detail.alpha="a b" detail.mid="" detail.zed=z`},
+		{"awkward detail keys",
+			errcat.ErrorDetailed(ErrAsdf, "a msg", map[string]string{"a key=": "v"}),
+			`category=err-asdf msg="a msg" detail."a key="=v`},
+		{"uncategorized errors",
+			fmt.Errorf("wild"),
+			`category=unknown-category msg=wild`},
+	} {
+		t.Run(tr.title, func(t *testing.T) {
+			line := errcat.FormatLogfmt(tr.err)
+			if line != tr.line {
+				t.Errorf("must match fixture -- got `%s`", line)
+			}
+			e2, err := errcat.ParseLogfmt(line)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if e2.Message() != tr.err.Error() {
+				t.Errorf("message must roundtrip -- got %q", e2.Message())
+			}
+			if len(e2.Details()) != 0 || len(errcat.Details(tr.err)) != 0 {
+				if !reflect.DeepEqual(e2.Details(), errcat.Details(tr.err)) {
+					t.Errorf("details must roundtrip -- got %v", e2.Details())
+				}
+			}
+		})
+	}
+	t.Run("nil errors render empty", func(t *testing.T) {
+		if line := errcat.FormatLogfmt(nil); line != "" {
+			t.Errorf("must be empty -- got `%s`", line)
+		}
+	})
+	t.Run("parsing ignores other keys", func(t *testing.T) {
+		e2, err := errcat.ParseLogfmt(`time=2017-01-01T00:00:00Z level=error category=err-xml msg="hello world" verbose detail.k=v`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		shouldCategory(t, e2, ErrXMLRegistered)
+		if e2.Message() != "hello world" || e2.Details()["k"] != "v" {
+			t.Errorf("must parse -- got %q %v", e2.Message(), e2.Details())
+		}
+	})
+	t.Run("parsing rejects lines without category", func(t *testing.T) {
+		_, err := errcat.ParseLogfmt(`level=error msg=hi`)
+		shouldCategory(t, err, errcat.ErrDecoding)
+	})
+	t.Run("empty categories parse as missing", func(t *testing.T) {
+		e2, err := errcat.ParseLogfmt(`category="" msg=hi`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		shouldCategory(t, e2, errcat.ErrMissingCategory)
+	})
+	t.Run("parsing rejects unterminated quotes", func(t *testing.T) {
+		_, err := errcat.ParseLogfmt(`category=x msg="hi`)
+		shouldCategory(t, err, errcat.ErrDecoding)
+	})
+}
@@ -0,0 +1,195 @@
+package errcat
+
+import "fmt"
+
+/*
+	Category types may implement any of these optional interfaces to describe
+	how errors of that category should be treated, so generic code
+	(middleware, retry loops, CLI mains) can make decisions without knowing
+	every package's categories by heart:
+
+		type ErrorCategory string
+
+		func (c ErrorCategory) Retryable() bool { return c == ErrBusy }
+		func (c ErrorCategory) HTTPStatus() int {
+			switch c {
+			case ErrNotFound:
+				return 404
+			case ErrBusy:
+				return 503
+			default:
+				return 500
+			}
+		}
+
+	Use the package funcs (`IsRetryable`, `SeverityOf`, `HTTPStatus`,
+	`ExitCode`) to consult them.  Categories which don't implement an
+	interface (including plain strings, e.g. from decoding an error of an
+	unregistered category) fall back to their entry in `DefaultCatalog`.
+*/
+type RetryableCategory interface {
+	Retryable() bool
+}
+
+/*
+	TemporaryCategory is the `net.Error`-style spelling of `RetryableCategory`;
+	`IsRetryable` honors either.
+*/
+type TemporaryCategory interface {
+	Temporary() bool
+}
+
+type SeverityCategory interface {
+	Severity() Severity
+}
+
+type HTTPStatusCategory interface {
+	HTTPStatus() int
+}
+
+type ExitCodeCategory interface {
+	ExitCode() int
+}
+
+/*
+	Severity says how bad an error is, for routing alerts and choosing log levels.
+	The zero value means unspecified.
+*/
+type Severity int
+
+const (
+	SeverityUnspecified Severity = iota
+	SeverityDebug
+	SeverityInfo
+	SeverityWarning
+	SeverityError
+	SeverityCritical
+)
+
+var severityNames = [...]string{"", "debug", "info", "warning", "error", "critical"}
+
+func (s Severity) String() string {
+	if s >= 0 && int(s) < len(severityNames) {
+		return severityNames[s]
+	}
+	return fmt.Sprintf("severity(%d)", int(s))
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Severity) UnmarshalText(text []byte) error {
+	for i, name := range severityNames {
+		if string(text) == name {
+			*s = Severity(i)
+			return nil
+		}
+	}
+	return Errorf(ErrDecoding, "errcat: unknown severity %q", text)
+}
+
+/*
+	Return whether the operation which failed with this error may succeed if
+	retried: per the category's `Retryable()` or `Temporary()` method if it
+	has one, or else its `DefaultCatalog` entry.
+
+	Nil errors, non-errcat errors, and categories nobody said anything about
+	are not retryable.
+*/
+func IsRetryable(err error) bool {
+	switch cat := Category(err).(type) {
+	case nil:
+		return false
+	case RetryableCategory:
+		return cat.Retryable()
+	case TemporaryCategory:
+		return cat.Temporary()
+	default:
+		return lookupMeta(cat).Retryable
+	}
+}
+
+/*
+	Return the severity of an error: per the category's `Severity()` method
+	if it has one, or else its `DefaultCatalog` entry.
+
+	Nil errors have `SeverityUnspecified`; errors whose severity nobody said
+	anything about are `SeverityError`.
+*/
+func SeverityOf(err error) Severity {
+	switch cat := Category(err).(type) {
+	case nil:
+		return SeverityUnspecified
+	case SeverityCategory:
+		return cat.Severity()
+	default:
+		if s := lookupMeta(cat).Severity; s != SeverityUnspecified {
+			return s
+		}
+		return SeverityError
+	}
+}
+
+/*
+	Return the HTTP status an error should be presented with: per the
+	category's `HTTPStatus()` method if it has one, or else its
+	`DefaultCatalog` entry.
+
+	Nil errors are 200; errors whose status nobody said anything about are 500.
+*/
+func HTTPStatus(err error) int {
+	switch cat := Category(err).(type) {
+	case nil:
+		return 200
+	case HTTPStatusCategory:
+		return cat.HTTPStatus()
+	default:
+		if s := lookupMeta(cat).HTTPStatus; s != 0 {
+			return s
+		}
+		return 500
+	}
+}
+
+/*
+	Return the process exit code an error should be presented with: per the
+	category's `ExitCode()` method if it has one, or else its
+	`DefaultCatalog` entry.
+
+	Nil errors are 0; errors whose exit code nobody said anything about are 1.
+*/
+func ExitCode(err error) int {
+	switch cat := Category(err).(type) {
+	case nil:
+		return 0
+	case ExitCodeCategory:
+		return cat.ExitCode()
+	default:
+		if c := lookupMeta(cat).ExitCode; c != 0 {
+			return c
+		}
+		return 1
+	}
+}
+
+/*
+	Find the `DefaultCatalog` entry for a category.
+	Plain strings match an entry for any type with the same serialized form,
+	since that's what an unregistered category looks like after decoding.
+*/
+func lookupMeta(category interface{}) CatalogEntry {
+	if e, ok := DefaultCatalog.Lookup(category); ok {
+		return e
+	}
+	if s, ok := category.(string); ok {
+		DefaultCatalog.mu.Lock()
+		defer DefaultCatalog.mu.Unlock()
+		for _, e := range DefaultCatalog.entries {
+			if e.Category == s {
+				return e
+			}
+		}
+	}
+	return CatalogEntry{}
+}
@@ -0,0 +1,89 @@
+package errcat_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/warpfork/go-errcat"
+)
+
+type MetaTestCategory string
+
+const (
+	ErrMetaBusy    = MetaTestCategory("err-meta-busy")
+	ErrMetaInvalid = MetaTestCategory("err-meta-invalid")
+)
+
+func (c MetaTestCategory) Retryable() bool { return c == ErrMetaBusy }
+func (c MetaTestCategory) HTTPStatus() int {
+	if c == ErrMetaBusy {
+		return 503
+	}
+	return 400
+}
+
+type CatalogedTestCategory string
+
+const ErrCatalogedThrottled = CatalogedTestCategory("err-cataloged-throttled")
+
+func init() {
+	errcat.DefaultCatalog.Add(ErrCatalogedThrottled, errcat.CatalogEntry{
+		Retryable:  true,
+		HTTPStatus: 429,
+		ExitCode:   75,
+		Severity:   errcat.SeverityWarning,
+	})
+}
+
+func TestCategoryMeta(t *testing.T) {
+	for _, tr := range []struct {
+		title     string
+		err       error
+		retryable bool
+		severity  errcat.Severity
+		status    int
+		exit      int
+	}{
+		{"nil", nil, false, errcat.SeverityUnspecified, 200, 0},
+		{"wild errors", fmt.Errorf("wild"), false, errcat.SeverityError, 500, 1},
+		{"methods on the category", errcat.Errorf(ErrMetaBusy, "busy"), true, errcat.SeverityError, 503, 1},
+		{"methods on the category, false", errcat.Errorf(ErrMetaInvalid, "bad"), false, errcat.SeverityError, 400, 1},
+		{"catalog entry", errcat.Errorf(ErrCatalogedThrottled, "slow down"), true, errcat.SeverityWarning, 429, 75},
+		{"catalog entry for plain strings", errcat.Errorf("err-cataloged-throttled", "slow down"), true, errcat.SeverityWarning, 429, 75},
+		{"nothing known", errcat.Errorf(ErrAsdf, "asdf"), false, errcat.SeverityError, 500, 1},
+		{"errcat's own categories", errcat.Errorf(errcat.ErrCategoryFilterRejection, "bug"), false, errcat.SeverityCritical, 500, 1},
+	} {
+		t.Run(tr.title, func(t *testing.T) {
+			if r := errcat.IsRetryable(tr.err); r != tr.retryable {
+				t.Errorf("expected retryable %v, got %v", tr.retryable, r)
+			}
+			if s := errcat.SeverityOf(tr.err); s != tr.severity {
+				t.Errorf("expected severity %v, got %v", tr.severity, s)
+			}
+			if s := errcat.HTTPStatus(tr.err); s != tr.status {
+				t.Errorf("expected status %d, got %d", tr.status, s)
+			}
+			if c := errcat.ExitCode(tr.err); c != tr.exit {
+				t.Errorf("expected exit code %d, got %d", tr.exit, c)
+			}
+		})
+	}
+}
+
+func TestSeveritySerialization(t *testing.T) {
+	bytes, err := json.Marshal(errcat.CatalogEntry{Category: "x", Severity: errcat.SeverityWarning})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(bytes) != `{"category":"x","severity":"warning"}` {
+		t.Errorf("must match fixture -- got `%s`", bytes)
+	}
+	var e errcat.CatalogEntry
+	if err := json.Unmarshal(bytes, &e); err != nil || e.Severity != errcat.SeverityWarning {
+		t.Errorf("must roundtrip -- got %v, %v", e.Severity, err)
+	}
+	if err := json.Unmarshal([]byte(`{"severity":"dire"}`), &e); err == nil {
+		t.Errorf("unknown severities must be rejected")
+	}
+}
@@ -0,0 +1,127 @@
+package errcat
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+/*
+	Namespaces keep categories from different packages apart once serialized.
+
+	Two packages may well both declare `ErrNotFound = Category("not-found")`;
+	as Go values they're distinct (they have different types), but serialized
+	they're both just "not-found", and there's no telling which to decode to.
+	Giving a category type a namespace makes its categories serialize
+	qualified -- "store:not-found" -- and decode back unambiguously.
+
+	A category type gets a namespace either from a `CategoryNamespace()`
+	method (see `NamespacedCategory`), or from `RegisterNamespace`; the
+	method wins if there's both.
+	Types without a namespace serialize unqualified, exactly as always.
+*/
+type NamespacedCategory interface {
+	CategoryNamespace() string
+}
+
+// NamespaceSeparator joins a namespace and category value in the serialized form.
+const NamespaceSeparator = ":"
+
+var categoryNamespaces = struct {
+	sync.RWMutex
+	m map[reflect.Type]string
+}{m: map[reflect.Type]string{}}
+
+/*
+	Give every category of the example value's type a namespace.
+
+	Call this from an `init` func in the package that declares the category
+	type; `PackageNamespace` is a handy source of unique namespaces:
+
+		func init() {
+			errcat.RegisterNamespace(ErrorCategory(""), errcat.PackageNamespace(ErrorCategory("")))
+			errcat.RegisterCategory(ErrNotFound, ErrConflict)
+		}
+
+	Categories of the type which were already registered with
+	`RegisterCategory` are re-registered under their new serialized form,
+	and so are their deprecations and their entries in `DefaultCatalog`.
+
+	Panics if the namespace is empty or contains `NamespaceSeparator`,
+	or if the type isn't a named type declared outside this package.
+*/
+func RegisterNamespace(example interface{}, namespace string) {
+	rt := reflect.TypeOf(example)
+	switch {
+	case namespace == "" || strings.Contains(namespace, NamespaceSeparator):
+		panic(fmt.Sprintf("errcat: invalid namespace %q", namespace))
+	case rt == nil || rt.Name() == "" || rt.PkgPath() == "":
+		panic(fmt.Sprintf("errcat: cannot namespace unnamed type %v", rt))
+	case rt.PkgPath() == reflect.TypeOf(unknown).PkgPath():
+		panic("errcat: cannot namespace errcat's own categories")
+	}
+	_, hasMethod := example.(NamespacedCategory)
+	categoryNamespaces.Lock()
+	oldNamespace := categoryNamespaces.m[rt]
+	categoryNamespaces.m[rt] = namespace
+	categoryNamespaces.Unlock()
+	if !hasMethod { // the method wins, so the serialized form doesn't change.
+		DefaultCatalog.requalify(typeString(rt), oldNamespace, namespace)
+	}
+
+	// Re-register any categories (and deprecations) of this type, since their keys just changed.
+	categoryRegistry.Lock()
+	defer categoryRegistry.Unlock()
+	var moved []interface{}
+	for k, cat := range categoryRegistry.m {
+		if reflect.TypeOf(cat) == rt {
+			moved = append(moved, cat)
+			delete(categoryRegistry.m, k)
+		}
+	}
+	for v, cats := range categoryRegistry.bare {
+		kept := cats[:0]
+		for _, cat := range cats {
+			if reflect.TypeOf(cat) != rt {
+				kept = append(kept, cat)
+			}
+		}
+		categoryRegistry.bare[v] = kept
+	}
+	for _, cat := range moved {
+		registerCategoryLocked(cat)
+	}
+	var movedDeprecations [][2]interface{}
+	for k, d := range categoryRegistry.deprecated {
+		if reflect.TypeOf(d[0]) == rt {
+			movedDeprecations = append(movedDeprecations, d)
+			delete(categoryRegistry.deprecated, k)
+		}
+	}
+	for _, d := range movedDeprecations {
+		categoryRegistry.deprecated[categoryString(d[0])] = d
+	}
+}
+
+/*
+	Return the path of the package declaring the example value's type,
+	for use as a namespace.
+*/
+func PackageNamespace(example interface{}) string {
+	return reflect.TypeOf(example).PkgPath()
+}
+
+// Return the namespace of a category, or empty if it has none.
+func namespaceOf(category interface{}) string {
+	switch c := category.(type) {
+	case nil, string, errorCategory:
+		return ""
+	case NamespacedCategory:
+		return c.CategoryNamespace()
+	}
+	categoryNamespaces.RLock()
+	ns := categoryNamespaces.m[reflect.TypeOf(category)]
+	categoryNamespaces.RUnlock()
+	return ns
+}
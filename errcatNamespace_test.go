@@ -0,0 +1,109 @@
+package errcat_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/warpfork/go-errcat"
+)
+
+type NamespaceTestStoreCategory string
+type NamespaceTestAuthCategory string
+type NamespaceTestLateCategory string
+type NamespaceTestCatalogedCategory string
+
+func (NamespaceTestAuthCategory) CategoryNamespace() string { return "auth" }
+
+const (
+	ErrStoreNotFound = NamespaceTestStoreCategory("ns-not-found")
+	ErrAuthNotFound  = NamespaceTestAuthCategory("ns-not-found")
+	ErrLateNotFound  = NamespaceTestLateCategory("ns-late-not-found")
+
+	ErrCatalogedLate = NamespaceTestCatalogedCategory("ns-cataloged")
+)
+
+func init() {
+	errcat.RegisterNamespace(NamespaceTestStoreCategory(""), errcat.PackageNamespace(NamespaceTestStoreCategory("")))
+	errcat.RegisterCategory(ErrStoreNotFound, ErrAuthNotFound)
+	errcat.RegisterCategory(ErrLateNotFound)
+	errcat.RegisterNamespace(NamespaceTestLateCategory(""), "late")
+	errcat.DefaultCatalog.Add(ErrCatalogedLate, errcat.CatalogEntry{HTTPStatus: 409})
+	errcat.RegisterNamespace(NamespaceTestCatalogedCategory(""), "first")
+	errcat.RegisterNamespace(NamespaceTestCatalogedCategory(""), "cataloged")
+}
+
+func TestNamespaces(t *testing.T) {
+	t.Run("serialization is qualified", func(t *testing.T) {
+		for _, tr := range []struct {
+			err    error
+			expect string
+		}{
+			{errcat.Errorf(ErrStoreNotFound, "a"), `{"category":"github.com/warpfork/go-errcat_test:ns-not-found","message":"a"}`},
+			{errcat.Errorf(ErrAuthNotFound, "a"), `{"category":"auth:ns-not-found","message":"a"}`},
+			{errcat.Errorf(ErrLateNotFound, "a"), `{"category":"late:ns-late-not-found","message":"a"}`},
+			{errcat.Errorf(ErrAsdf, "a"), `{"category":"err-asdf","message":"a"}`},
+		} {
+			bytes, err := json.Marshal(tr.err)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(bytes) != tr.expect {
+				t.Errorf("must match fixture -- got `%s`", bytes)
+			}
+		}
+	})
+	t.Run("decoding resolves qualified forms", func(t *testing.T) {
+		for _, cat := range []interface{}{ErrStoreNotFound, ErrAuthNotFound, ErrLateNotFound} {
+			bytes, _ := json.Marshal(errcat.Errorf(cat, "a"))
+			e2, err := errcat.ParseJSON(bytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			shouldCategory(t, e2, cat)
+		}
+	})
+	t.Run("decoding resolves unambiguous unqualified forms", func(t *testing.T) {
+		shouldCategory(t, mustParseJSON(t, `{"category":"ns-late-not-found"}`), ErrLateNotFound)
+	})
+	t.Run("decoding leaves ambiguous unqualified forms alone", func(t *testing.T) {
+		shouldCategory(t, mustParseJSON(t, `{"category":"ns-not-found"}`), "ns-not-found")
+	})
+	t.Run("duplicate registrations are reported", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("must panic")
+			}
+		}()
+		errcat.RegisterCategory(NamespaceTestAuthCategory("ns-dupe"), "auth:ns-dupe")
+	})
+	t.Run("repeat registrations are harmless", func(t *testing.T) {
+		errcat.RegisterCategory(ErrStoreNotFound)
+	})
+	t.Run("catalog entries follow their category into a namespace", func(t *testing.T) {
+		e, ok := errcat.DefaultCatalog.Lookup(ErrCatalogedLate)
+		if !ok || e.Category != "cataloged:ns-cataloged" || e.HTTPStatus != 409 {
+			t.Errorf("expected the entry under its qualified form, got %#v (found: %v)", e, ok)
+		}
+	})
+	t.Run("invalid namespaces are rejected", func(t *testing.T) {
+		for _, ns := range []string{"", "a:b"} {
+			func() {
+				defer func() {
+					if recover() == nil {
+						t.Errorf("namespace %q must panic", ns)
+					}
+				}()
+				errcat.RegisterNamespace(ErrorCategory(""), ns)
+			}()
+		}
+	})
+}
+
+func mustParseJSON(t *testing.T, s string) errcat.Error {
+	t.Helper()
+	e, err := errcat.ParseJSON([]byte(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
@@ -0,0 +1,179 @@
+package errcat
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+/*
+	Return a new error with the given category and message, and whatever
+	else the options say:
+
+		return errcat.New(ErrDataCorruption, "checksum mismatch in %s",
+			errcat.WithArgs(filename),
+			errcat.WithCause(err),
+			errcat.WithDetailPairs("want", want, "got", got),
+			errcat.WithCaller(),
+		)
+
+	This is the same as combining `Errorf`, `AppendDetail`, and so on, but
+	the error is built in one go, rather than copied at each step, so it
+	takes fewer allocations: one for the error; one more if it has a cause,
+	time, annotations, or captured frames (however many of those it has);
+	plus the details map (if there are any details, however many options
+	set them); plus whatever the options need for themselves (e.g.
+	formatting with `WithArgs`).
+
+	Options are applied in order; where two options set the same thing
+	(e.g. the same detail key), the later one wins.
+	The category is checked exactly as the other factories check it
+	(see `SetStrictCategories`).
+*/
+func New(category interface{}, msg string, opts ...Option) error {
+	e := &errStruct{Category_: category, Message_: msg}
+	for _, opt := range opts {
+		opt(e)
+	}
+	if e.frames() == nil {
+		e.setFrames(capturedFrames())
+	}
+	if len(e.annotations()) > 0 {
+		e.Message_ = strings.Join(e.annotations(), ": ") + ": " + e.Message_
+	}
+	e.Category_, e.Message_ = checkCategory(e.Category_, e.Message_)
+	return e
+}
+
+/*
+	Option configures an error made by `New`.
+*/
+type Option func(e *errStruct)
+
+/*
+	Use the message as a format string, with these args, as `Errorf` would.
+*/
+func WithArgs(args ...interface{}) Option {
+	return func(e *errStruct) {
+		e.Message_ = fmt.Sprintf(e.Message_, args...)
+	}
+}
+
+/*
+	Record the error that caused this one.
+	It's returned by the error's `Unwrap` method, so `errors.Is` and
+	`errors.As` see it, and it's kept when the error is serialized
+	(non-errcat causes are serialized as if they had the `unknown` category).
+
+	Typed nils count as nil here, regardless of build tags, as with `Box`:
+	they'd only blow up later, when the error is printed or serialized.
+	(With the `errcat_debug` tag, they're reported too; see `TypedNilReport`.)
+*/
+func WithCause(cause error) Option {
+	return func(e *errStruct) {
+		c := denil(cause, "WithCause")
+		if c != nil && isTypedNil(c) {
+			c = nil
+		}
+		if c != nil || e.extras != nil {
+			e.ext().Cause_ = c
+		}
+	}
+}
+
+/*
+	Add a detail.
+*/
+func WithDetail(key, value string) Option {
+	return func(e *errStruct) {
+		e.setDetail(key, value)
+	}
+}
+
+// Only for use while the error is being built; the map is shared once it's out.
+func (e *errStruct) setDetail(key, value string) {
+	if e.Details_ == nil {
+		e.Details_ = make(map[string]string)
+	}
+	e.Details_[key] = value
+}
+
+/*
+	Add all the details in the map.  (The map is copied, not kept.)
+*/
+func WithDetails(details map[string]string) Option {
+	return func(e *errStruct) {
+		for k, v := range details {
+			e.setDetail(k, v)
+		}
+	}
+}
+
+/*
+	Add details given as alternating keys and values.
+	If there's an odd number of strings, the last key gets an empty value.
+*/
+func WithDetailPairs(kv ...string) Option {
+	return func(e *errStruct) {
+		for i := 0; i < len(kv); i += 2 {
+			var v string
+			if i+1 < len(kv) {
+				v = kv[i+1]
+			}
+			e.setDetail(kv[i], v)
+		}
+	}
+}
+
+/*
+	Record where `New` was called from; see `Frames`.
+*/
+func WithCaller() Option {
+	return func(e *errStruct) {
+		e.setFrames(captureFrames(1))
+	}
+}
+
+/*
+	Record the current time as when the error happened; see `Timestamp`.
+*/
+func WithTimestamp() Option {
+	return WithTime(time.Now())
+}
+
+/*
+	Record the given time as when the error happened; see `Timestamp`.
+*/
+func WithTime(t time.Time) Option {
+	return func(e *errStruct) {
+		if !t.IsZero() || e.extras != nil {
+			e.ext().Time_ = t
+		}
+	}
+}
+
+/*
+	Prefix the message with a bit of context, as `PrefixAnnotate` would
+	(but without the templating).
+	Each annotation goes outside the ones before it, so:
+
+		errcat.New(cat, "disk full", errcat.WithAnnotation("writing foo"), errcat.WithAnnotation("saving"))
+
+	has the message "saving: writing foo: disk full".
+*/
+func WithAnnotation(prefix string) Option {
+	return func(e *errStruct) {
+		e.ext().Annotations_ = append([]string{prefix}, e.annotations()...)
+	}
+}
+
+/*
+	Return the time recorded when the error was made (see `WithTimestamp`),
+	or the zero time if none was (or the error is nil, or isn't one of ours).
+*/
+func Timestamp(err error) time.Time {
+	if e, ok := denil(err, "Timestamp").(*errStruct); ok {
+		return e.when()
+	}
+	return time.Time{}
+}
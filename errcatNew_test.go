@@ -0,0 +1,150 @@
+package errcat_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/warpfork/go-errcat"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("plain", func(t *testing.T) {
+		err := errcat.New(ErrAsdf, "100%")
+		shouldCategory(t, err, ErrAsdf)
+		if err.Error() != "100%" {
+			t.Errorf("message must not be formatted without args, got %q", err.Error())
+		}
+	})
+	t.Run("args", func(t *testing.T) {
+		err := errcat.New(ErrAsdf, "%d%%", errcat.WithArgs(100))
+		if err.Error() != "100%" {
+			t.Errorf("got %q", err.Error())
+		}
+	})
+	t.Run("details, later options winning", func(t *testing.T) {
+		err := errcat.New(ErrAsdf, "msg",
+			errcat.WithDetail("a", "1"),
+			errcat.WithDetails(map[string]string{"a": "2", "b": "2"}),
+			errcat.WithDetailPairs("b", "3", "c", "3", "d"),
+		)
+		expect := map[string]string{"a": "2", "b": "3", "c": "3", "d": ""}
+		if !reflect.DeepEqual(errcat.Details(err), expect) {
+			t.Errorf("expected %v, got %v", expect, errcat.Details(err))
+		}
+	})
+	t.Run("cause", func(t *testing.T) {
+		err := errcat.New(ErrAsdf, "couldn't read", errcat.WithCause(io.EOF))
+		if !errors.Is(err, io.EOF) {
+			t.Errorf("must unwrap to the cause")
+		}
+		if errors.Unwrap(errcat.Errorf(ErrAsdf, "no cause")) != nil {
+			t.Errorf("errors without a cause must unwrap to nil")
+		}
+	})
+	t.Run("timestamp", func(t *testing.T) {
+		before := time.Now()
+		err := errcat.New(ErrAsdf, "msg", errcat.WithTimestamp())
+		if ts := errcat.Timestamp(err); ts.Before(before) || ts.After(time.Now()) {
+			t.Errorf("timestamp %v out of range", ts)
+		}
+		if !errcat.Timestamp(errcat.Errorf(ErrAsdf, "msg")).IsZero() {
+			t.Errorf("errors without a timestamp must report the zero time")
+		}
+	})
+	t.Run("caller", func(t *testing.T) {
+		err := errcat.New(ErrAsdf, "msg", errcat.WithCaller())
+		frames := errcat.Frames(err)
+		if len(frames) != 1 {
+			t.Fatalf("expected 1 frame, got %v", frames)
+		}
+		if filepath.Base(frames[0].File) != "errcatNew_test.go" || !strings.HasPrefix(frames[0].Function, "github.com/warpfork/go-errcat_test.TestNew.") {
+			t.Errorf("wrong frame: %v", frames[0])
+		}
+	})
+	t.Run("annotations", func(t *testing.T) {
+		err := errcat.New(ErrAsdf, "disk full", errcat.WithAnnotation("writing foo"), errcat.WithAnnotation("saving"))
+		if err.Error() != "saving: writing foo: disk full" {
+			t.Errorf("got %q", err.Error())
+		}
+	})
+	t.Run("invalid categories", func(t *testing.T) {
+		err := errcat.New(nil, "msg %s", errcat.WithArgs("here"))
+		shouldCategory(t, err, errcat.ErrInvalidCategory)
+		if err.Error() != "errcat: invalid category of type <nil>: category must not be nil (original error: msg here)" {
+			t.Errorf("got %q", err.Error())
+		}
+	})
+	t.Run("derived errors keep cause and time", func(t *testing.T) {
+		when := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+		orig := errcat.New(ErrAsdf, "msg", errcat.WithCause(io.EOF), errcat.WithTime(when))
+		for name, err := range map[string]error{
+			"Recategorize":   errcat.Recategorize(ErrorCategoryA("a"), orig),
+			"AppendDetail":   errcat.AppendDetail(orig, "k", "v"),
+			"PrefixAnnotate": errcat.PrefixAnnotate(orig, "ctx", nil),
+		} {
+			if !errors.Is(err, io.EOF) || !errcat.Timestamp(err).Equal(when) {
+				t.Errorf("%s: lost cause or time", name)
+			}
+		}
+	})
+	t.Run("typed nil causes are dropped", func(t *testing.T) {
+		var cause *otherCopyError
+		err := errcat.New(ErrAsdf, "msg", errcat.WithCause(cause))
+		if errors.Unwrap(err) != nil {
+			t.Errorf("expected no cause, got %#v", errors.Unwrap(err))
+		}
+		if _, err := errcat.DebugJSON(err); err != nil {
+			t.Error(err)
+		}
+		if s := fmt.Sprintf("%+v", err); s == "" {
+			t.Errorf("expected output")
+		}
+	})
+	t.Run("one allocation for a plain error", func(t *testing.T) {
+		allocs := testing.AllocsPerRun(100, func() {
+			errcat.New(ErrAsdf, "msg")
+		})
+		if allocs != 1 {
+			t.Errorf("expected 1 allocation, got %v", allocs)
+		}
+	})
+	t.Run("one more for a cause and time", func(t *testing.T) {
+		cause := errcat.Errorf(ErrAsdf, "cause")
+		when := time.Now()
+		allocs := testing.AllocsPerRun(100, func() {
+			errcat.New(ErrAsdf, "msg", errcat.WithCause(cause), errcat.WithTime(when))
+		})
+		if allocs != 2 {
+			t.Errorf("expected 2 allocations, got %v", allocs)
+		}
+	})
+	t.Run("details cost the same however many options set them", func(t *testing.T) {
+		one := testing.AllocsPerRun(100, func() {
+			errcat.New(ErrAsdf, "msg", errcat.WithDetail("a", "1"))
+		})
+		three := testing.AllocsPerRun(100, func() {
+			errcat.New(ErrAsdf, "msg", errcat.WithDetail("a", "1"), errcat.WithDetail("b", "2"), errcat.WithDetail("c", "3"))
+		})
+		if one != three {
+			t.Errorf("expected the same allocations, got %v for one detail and %v for three", one, three)
+		}
+	})
+	t.Run("fewer allocations than chaining", func(t *testing.T) {
+		cause := errcat.Errorf(ErrAsdf, "cause")
+		built := testing.AllocsPerRun(100, func() {
+			errcat.New(ErrAsdf, "msg", errcat.WithCause(cause), errcat.WithDetail("a", "1"), errcat.WithDetail("b", "2"))
+		})
+		chained := testing.AllocsPerRun(100, func() {
+			errcat.AppendDetail(errcat.AppendDetail(errcat.Errorf(ErrAsdf, "msg"), "a", "1"), "b", "2")
+		})
+		if built >= chained {
+			t.Errorf("expected fewer than %v allocations, got %v", chained, built)
+		}
+	})
+}
@@ -0,0 +1,91 @@
+package errcat
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+)
+
+/*
+	Typed nils -- an `Error` interface holding a nil pointer, as you get from
+	a `*MyError` field in a struct that was never set -- are not nil as far as
+	`err == nil` is concerned, so they sail past our nil checks and then panic
+	somewhere deep inside `Category()` or `Details()`.
+
+	Checking for them costs a reflect call on every accessor, which is more
+	than we're willing to charge by default (see `_rsrch/nilly`).
+	So it's opt-in: build with `-tags errcat_debug`, and all the accessors and
+	factories check for typed nils, treat them as plain nil, and report each one
+	-- with the file and line that handed it to us -- to the reporter
+	(see `SetTypedNilReporter`).
+	Without the tag, the checks compile away entirely.
+*/
+type TypedNilReport struct {
+	Type string // The dynamic type of the typed nil, e.g. "*mypkg.MyError".
+	Func string // The errcat function it was given to, e.g. "Category".
+	File string // Where that errcat function was called from.  (The first frame outside this package.)
+	Line int
+}
+
+func (r TypedNilReport) String() string {
+	return fmt.Sprintf("errcat: typed nil %s given to errcat.%s at %s:%d; treating it as nil", r.Type, r.Func, r.File, r.Line)
+}
+
+/*
+	Set the function which receives a report of each typed nil error found,
+	when built with the `errcat_debug` tag.
+	The default prints the report to stderr.
+	Setting nil restores the default.
+
+	Without the `errcat_debug` tag, no checks are made and the reporter is
+	never called.
+*/
+func SetTypedNilReporter(fn func(TypedNilReport)) {
+	typedNilReporter.Lock()
+	defer typedNilReporter.Unlock()
+	typedNilReporter.fn = fn
+}
+
+var typedNilReporter struct {
+	sync.Mutex
+	fn func(TypedNilReport)
+}
+
+/*
+	Return err, or nil if err is a typed nil (and we're in debug mode;
+	otherwise, this is a no-op, and the compiler knows it).
+	`fn` names the calling errcat function, for the report.
+*/
+func denil(err error, fn string) error {
+	if !nilDebug || err == nil || !isTypedNil(err) {
+		return err
+	}
+	reportTypedNil(fmt.Sprintf("%T", err), fn)
+	return nil
+}
+
+func isTypedNil(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan, reflect.Interface:
+		return rv.IsNil()
+	default:
+		return false
+	}
+}
+
+func reportTypedNil(typ string, fn string) {
+	r := TypedNilReport{Type: typ, Func: fn, File: "?"}
+	if frames := captureFrames(1); len(frames) > 0 {
+		r.File, r.Line = frames[0].File, frames[0].Line
+	}
+	typedNilReporter.Lock()
+	report := typedNilReporter.fn
+	typedNilReporter.Unlock()
+	if report == nil {
+		fmt.Fprintln(os.Stderr, r)
+		return
+	}
+	report(r)
+}
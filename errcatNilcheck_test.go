@@ -0,0 +1,132 @@
+//go:build errcat_debug
+// +build errcat_debug
+
+package errcat_test
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"github.com/warpfork/go-errcat"
+)
+
+// Methods which dereference without checking, as most people write them.
+type nillyError struct {
+	msg string
+}
+
+func (e *nillyError) Category() interface{}      { return ErrAsdf }
+func (e *nillyError) Message() string            { return e.msg }
+func (e *nillyError) Details() map[string]string { return nil }
+func (e *nillyError) Error() string              { return e.msg }
+
+type nillyContainer struct {
+	Error *nillyError
+}
+
+func captureTypedNils(t *testing.T) *[]errcat.TypedNilReport {
+	var reports []errcat.TypedNilReport
+	errcat.SetTypedNilReporter(func(r errcat.TypedNilReport) { reports = append(reports, r) })
+	t.Cleanup(func() { errcat.SetTypedNilReporter(nil) })
+	return &reports
+}
+
+func TestTypedNils(t *testing.T) {
+	t.Run("accessors treat typed nils as nil", func(t *testing.T) {
+		reports := captureTypedNils(t)
+		var err error = nillyContainer{}.Error
+		if cat := errcat.Category(err); cat != nil {
+			t.Errorf("expected nil category, got %v", cat)
+		}
+		if details := errcat.Details(err); details != nil {
+			t.Errorf("expected nil details, got %v", details)
+		}
+		if errcat.Is(err, ErrAsdf) {
+			t.Errorf("typed nil must match nothing")
+		}
+		if code := errcat.GRPCCodeOf(err); code != errcat.GRPCCodeOK {
+			t.Errorf("expected OK, got %s", code)
+		}
+		if s := errcat.FormatLogfmt(err); s != "" {
+			t.Errorf("expected empty logfmt, got %q", s)
+		}
+		if len(*reports) != 5 {
+			t.Errorf("expected 5 reports, got %d: %v", len(*reports), *reports)
+		}
+	})
+	t.Run("factories treat typed nils as nil", func(t *testing.T) {
+		captureTypedNils(t)
+		var err error = nillyContainer{}.Error
+		if err2 := errcat.Recategorize(ErrAsdf, err); err2 != nil {
+			t.Errorf("expected nil, got %v", err2)
+		}
+		if err2 := errcat.AppendDetail(err, "k", "v"); err2 != nil {
+			t.Errorf("expected nil, got %v", err2)
+		}
+		if err2 := errcat.PrefixAnnotate(err, "prefix", nil); err2 != nil {
+			t.Errorf("expected nil, got %v", err2)
+		}
+	})
+	t.Run("typed nil causes are reported", func(t *testing.T) {
+		reports := captureTypedNils(t)
+		err := errcat.New(ErrAsdf, "msg", errcat.WithCause(nillyContainer{}.Error))
+		if cause := errors.Unwrap(err); cause != nil {
+			t.Errorf("expected no cause, got %#v", cause)
+		}
+		if len(*reports) != 1 || (*reports)[0].Func != "WithCause" {
+			t.Errorf("expected one report from WithCause, got %v", *reports)
+		}
+	})
+	t.Run("filters clear typed nils to true nils", func(t *testing.T) {
+		captureTypedNils(t)
+		err := func() (err error) {
+			defer errcat.RequireErrorHasCategory(&err, ErrorCategoryA(""))
+			return nillyContainer{}.Error
+		}()
+		if err != nil {
+			t.Errorf("expected a true nil, got %#v", err)
+		}
+	})
+	t.Run("reports name the caller", func(t *testing.T) {
+		reports := captureTypedNils(t)
+		errcat.IsRetryable(nillyContainer{}.Error)
+		if len(*reports) != 1 {
+			t.Fatalf("expected 1 report, got %d", len(*reports))
+		}
+		r := (*reports)[0]
+		if r.Type != "*errcat_test.nillyError" {
+			t.Errorf("wrong type: %q", r.Type)
+		}
+		if r.Func != "Category" {
+			t.Errorf("wrong func: %q", r.Func)
+		}
+		if filepath.Base(r.File) != "errcatNilcheck_test.go" || r.Line == 0 {
+			t.Errorf("wrong location: %s:%d", r.File, r.Line)
+		}
+	})
+	t.Run("slog handlers log typed nils as nil", func(t *testing.T) {
+		reports := captureTypedNils(t)
+		var buf bytes.Buffer
+		logger := newTestSlogger(&buf, func(h slog.Handler) slog.Handler { return errcat.NewSlogHandler(h, nil) })
+		logger.Info("hi", "err", nillyContainer{}.Error)
+		if got := buf.String(); got != "level=INFO msg=hi err=<nil>\n" {
+			t.Errorf("got %q", got)
+		}
+		if len(*reports) != 1 {
+			t.Fatalf("expected 1 report, got %v", *reports)
+		}
+		if r := (*reports)[0]; r.Func != "SlogHandler" || filepath.Base(r.File) != "errcatNilcheck_test.go" {
+			t.Errorf("wrong report: %v", r)
+		}
+	})
+	t.Run("non-nil errors are untouched", func(t *testing.T) {
+		reports := captureTypedNils(t)
+		shouldCategory(t, &nillyError{"hi"}, ErrAsdf)
+		if len(*reports) != 0 {
+			t.Errorf("expected no reports, got %v", *reports)
+		}
+	})
+}
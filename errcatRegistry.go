@@ -0,0 +1,213 @@
+package errcat
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+/*
+	The category registry maps the serialized string form of categories back
+	to their typed values.
+
+	Serialization only ever writes the string form of a category, so when an
+	error is decoded again -- from gob, xml, json, or whatever else -- the
+	typed value your `switch errcat.Category(err)` is looking for can only be
+	recovered if someone told us about it.
+	Decoders consult this registry; categories that aren't registered come
+	back as plain strings.
+*/
+var categoryRegistry = struct {
+	sync.RWMutex
+	m          map[string]interface{}    // serialized form -> category.
+	bare       map[string][]interface{}  // unqualified form -> every namespaced category with that form.
+	aliases    map[string]interface{}    // legacy serialized form -> current category.
+	deprecated map[string][2]interface{} // serialized form of a deprecated category -> it and its replacement.
+}{m: map[string]interface{}{
+	string(unknown):                    unknown,
+	string(ErrCategoryFilterRejection): ErrCategoryFilterRejection,
+	string(ErrDecoding):                ErrDecoding,
+	string(ErrMissingCategory):         ErrMissingCategory,
+	string(ErrInvalidCategory):         ErrInvalidCategory,
+}, bare: map[string][]interface{}{}, aliases: map[string]interface{}{}, deprecated: map[string][2]interface{}{}}
+
+/*
+	Declare category values, so that decoders can restore errors with these
+	categories to their typed values.
+
+	Typically called from an `init` func in the package that declares the
+	category consts:
+
+		func init() {
+			errcat.RegisterCategory(ErrAlreadyDone, ErrDataCorruption)
+		}
+
+	Registering two different categories with the same serialized form
+	panics, since they'd be indistinguishable once serialized: the message
+	names both types, and the fix is to give one of them a namespace (see
+	`RegisterNamespace`).  So does registering a category whose serialized
+	form is already an alias (see `RegisterAlias`).
+	Registering the same category again is harmless.
+*/
+func RegisterCategory(categories ...interface{}) {
+	categoryRegistry.Lock()
+	defer categoryRegistry.Unlock()
+	for _, cat := range categories {
+		registerCategoryLocked(cat)
+	}
+}
+
+func registerCategoryLocked(cat interface{}) {
+	key := categoryString(cat)
+	if existing, ok := categoryRegistry.m[key]; ok {
+		if existing == cat {
+			return
+		}
+		panic(fmt.Sprintf("errcat: category %q registered twice: as %s and as %s; give one of the types a namespace (see errcat.RegisterNamespace)",
+			key, typeString(reflect.TypeOf(existing)), typeString(reflect.TypeOf(cat))))
+	}
+	if current, ok := categoryRegistry.aliases[key]; ok {
+		panic(fmt.Sprintf("errcat: cannot register category %q: it is already an alias for %s(%q)", key, typeString(reflect.TypeOf(current)), current))
+	}
+	categoryRegistry.m[key] = cat
+	if namespaceOf(cat) != "" {
+		v := categoryValueString(cat)
+		categoryRegistry.bare[v] = append(categoryRegistry.bare[v], cat)
+	}
+}
+
+/*
+	Declare that a serialized form no longer used by any category
+	(typically because the category was renamed) should decode as the given
+	current category.
+
+	This lets long-lived data (queues, caches, logs) written with the old
+	spelling keep decoding after a rename.
+	See also `SetRecordOriginalCategory`.
+
+	Panics if the old form is that of a registered category.
+*/
+func RegisterAlias(old string, current interface{}) {
+	categoryRegistry.Lock()
+	defer categoryRegistry.Unlock()
+	if existing, ok := categoryRegistry.m[old]; ok {
+		panic(fmt.Sprintf("errcat: cannot alias %q: it is the serialized form of registered category %s(%q)", old, typeString(reflect.TypeOf(existing)), existing))
+	}
+	categoryRegistry.aliases[old] = current
+}
+
+/*
+	Declare that a category is deprecated in favor of a replacement.
+
+	Errors decoded with the deprecated category get the replacement instead,
+	and the deprecation hook (see `SetDeprecationHook`) is told, so you can
+	find whoever is still producing them.
+	Errors constructed with the deprecated category in this process are not
+	affected; the const still works until you remove it (at which point,
+	switch to `RegisterAlias`).
+*/
+func RegisterDeprecated(deprecated interface{}, replacement interface{}) {
+	categoryRegistry.Lock()
+	defer categoryRegistry.Unlock()
+	categoryRegistry.deprecated[categoryString(deprecated)] = [2]interface{}{deprecated, replacement}
+}
+
+// DetailOriginalCategory is the detail key under which decoders record a category's legacy spelling; see `SetRecordOriginalCategory`.
+const DetailOriginalCategory = "errcat-original-category"
+
+var legacyOptions = struct {
+	sync.RWMutex
+	recordOriginal  bool
+	deprecationHook func(deprecated, replacement interface{})
+}{}
+
+/*
+	When set, errors decoded with an aliased or deprecated category record
+	the category as it was spelled in the data, in a detail under the key
+	`DetailOriginalCategory`.
+	Off by default.
+*/
+func SetRecordOriginalCategory(record bool) {
+	legacyOptions.Lock()
+	legacyOptions.recordOriginal = record
+	legacyOptions.Unlock()
+}
+
+/*
+	Set a function to be called whenever a decoder maps a deprecated category
+	to its replacement (for logging, or counting, or failing tests).
+	Nil turns the hook off, which is the default.
+
+	The hook is called synchronously from the decoder, so should be quick.
+*/
+func SetDeprecationHook(hook func(deprecated, replacement interface{})) {
+	legacyOptions.Lock()
+	legacyOptions.deprecationHook = hook
+	legacyOptions.Unlock()
+}
+
+/*
+	Return the registered category value whose serialized form is the given
+	string, or the string itself if no such category has been registered.
+
+	An unqualified string also resolves to a namespaced category with that
+	value, as long as there's exactly one -- so data serialized before a
+	category type was given a namespace still decodes.
+
+	Aliased and deprecated categories resolve to their current replacement
+	(see `RegisterAlias` and `RegisterDeprecated`).
+*/
+func ResolveCategory(s string) interface{} {
+	cat, _, _ := resolveCategory(s)
+	return cat
+}
+
+/*
+	Resolve a category, also returning whether it came via an alias or
+	deprecation, and if the latter, the deprecated category.
+*/
+func resolveCategory(s string) (cat interface{}, legacy bool, deprecated interface{}) {
+	categoryRegistry.RLock()
+	defer categoryRegistry.RUnlock()
+	if d, ok := categoryRegistry.deprecated[s]; ok {
+		return d[1], true, d[0]
+	}
+	if cat, ok := categoryRegistry.aliases[s]; ok {
+		return cat, true, nil
+	}
+	if cat, ok := categoryRegistry.m[s]; ok {
+		return cat, false, nil
+	}
+	if cats := categoryRegistry.bare[s]; len(cats) == 1 {
+		if d, ok := categoryRegistry.deprecated[categoryString(cats[0])]; ok {
+			return d[1], true, d[0]
+		}
+		return cats[0], false, nil
+	}
+	return s, false, nil
+}
+
+/*
+	Construct an error from its decoded parts, resolving the category.
+	All decoders should come through here, so they agree: in particular, an
+	empty category becomes `ErrMissingCategory`, whatever the format.
+*/
+func decodedError(category string, msg string, details map[string]string) *errStruct {
+	if category == "" {
+		return &errStruct{Category_: ErrMissingCategory, Message_: msg, Details_: details}
+	}
+	cat, legacy, deprecated := resolveCategory(category)
+	if !legacy {
+		return &errStruct{Category_: cat, Message_: msg, Details_: details}
+	}
+	legacyOptions.RLock()
+	recordOriginal, hook := legacyOptions.recordOriginal, legacyOptions.deprecationHook
+	legacyOptions.RUnlock()
+	if recordOriginal {
+		details = mergeDetails(details, DetailOriginalCategory, category)
+	}
+	if deprecated != nil && hook != nil {
+		hook(deprecated, cat)
+	}
+	return &errStruct{Category_: cat, Message_: msg, Details_: details}
+}
@@ -0,0 +1,7 @@
+//go:build !errcat_debug
+// +build !errcat_debug
+
+package errcat
+
+// The default: no typed nil checks.  See `TypedNilReport`.
+const nilDebug = false
@@ -0,0 +1,207 @@
+package errcat
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+/*
+	LogValue makes errcat errors show up in `log/slog` output as a group,
+	rather than just their message:
+
+		err.category=err-storage err.message="disk full" err.details.path=/tmp/foo
+
+	The group holds "category" and "message"; "details" (a group, sorted by
+	key), "time", and "cause" (likewise expanded, if it's an errcat error)
+	are added when present.
+*/
+func (e *errStruct) LogValue() slog.Value {
+	return errorLogValue(e)
+}
+
+func errorLogValue(e Error) slog.Value {
+	attrs := []slog.Attr{
+		slog.String("category", categoryString(Category(e))),
+		slog.String("message", e.Message()),
+	}
+	if details := e.Details(); len(details) > 0 {
+		keys := sortedDetailKeys(details)
+		detailAttrs := make([]slog.Attr, len(keys))
+		for i, k := range keys {
+			detailAttrs[i] = slog.String(k, details[k])
+		}
+		attrs = append(attrs, slog.Attr{Key: "details", Value: slog.GroupValue(detailAttrs...)})
+	}
+	if t := Timestamp(e); !t.IsZero() {
+		attrs = append(attrs, slog.Time("time", t))
+	}
+	if cause := errors.Unwrap(e); cause != nil {
+		attrs = append(attrs, slog.Attr{Key: "cause", Value: errorValue(cause)})
+	}
+	return slog.GroupValue(attrs...)
+}
+
+/*
+	Return the value to log for any error: the group from `errorLogValue` for
+	errcat errors; a group of "message" and "cause" for other errors which
+	wrap an errcat error somewhere in their chain; or just the message.
+*/
+func errorValue(err error) slog.Value {
+	if e, ok := err.(Error); ok {
+		return errorLogValue(e)
+	}
+	if cause := errors.Unwrap(err); cause != nil && wrapsErrcat(cause) {
+		return slog.GroupValue(slog.String("message", err.Error()), slog.Attr{Key: "cause", Value: errorValue(cause)})
+	}
+	return slog.StringValue(err.Error())
+}
+
+// Return the first errcat error in the chain of (single) causes, or nil.
+func firstErrcat(err error) Error {
+	for ; err != nil; err = errors.Unwrap(err) {
+		if e, ok := err.(Error); ok {
+			return e
+		}
+	}
+	return nil
+}
+
+func wrapsErrcat(err error) bool {
+	return firstErrcat(err) != nil
+}
+
+/*
+	SlogHandler wraps another `slog.Handler`, expanding errcat errors found
+	in attributes -- including errors which merely wrap an errcat error
+	(e.g. with `fmt.Errorf("...: %w", err)`), and errcat errors from other
+	copies of this package, which `LogValue` can't reach -- into groups,
+	as described for `LogValue`.
+	Attributes inside groups are expanded too.
+
+	It can also copy the category of the logged error into a top-level
+	attribute of its own, which makes indexing and alerting on it easier;
+	see `SlogHandlerOptions`.
+*/
+type SlogHandler struct {
+	next  slog.Handler
+	opts  SlogHandlerOptions
+	goas  []slogGroupOrAttrs // from WithGroup and WithAttrs, outermost first; we apply them ourselves, so the category can stay top-level.
+	first Error              // the first errcat error among the goas attrs.
+}
+
+type SlogHandlerOptions struct {
+	/*
+		If set, records with an errcat error among their attributes get a
+		top-level attribute with this key (e.g. "error_category"), holding
+		the category of that error.
+		If there are several, the first one in the record's own attributes
+		wins, then the first in attributes added by `WithAttrs`.
+	*/
+	CategoryKey string
+}
+
+type slogGroupOrAttrs struct {
+	group string // if non-empty, this is a group, and attrs is empty.
+	attrs []slog.Attr
+}
+
+/*
+	Return a handler which expands errcat errors, then passes records on
+	to the next handler.  Options may be nil.
+*/
+func NewSlogHandler(next slog.Handler, opts *SlogHandlerOptions) *SlogHandler {
+	h := &SlogHandler{next: next}
+	if opts != nil {
+		h.opts = *opts
+	}
+	return h
+}
+
+func (h *SlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	var first Error
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, expandSlogAttr(a, &first))
+		return true
+	})
+	if first == nil {
+		first = h.first
+	}
+	for i := len(h.goas) - 1; i >= 0; i-- {
+		goa := h.goas[i]
+		switch {
+		case goa.group == "":
+			attrs = append(goa.attrs[:len(goa.attrs):len(goa.attrs)], attrs...)
+		case len(attrs) > 0: // empty groups are omitted, as slog does.
+			attrs = []slog.Attr{{Key: goa.group, Value: slog.GroupValue(attrs...)}}
+		}
+	}
+	r2 := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r2.AddAttrs(attrs...)
+	if h.opts.CategoryKey != "" && first != nil {
+		r2.AddAttrs(slog.String(h.opts.CategoryKey, categoryString(Category(first))))
+	}
+	return h.next.Handle(ctx, r2)
+}
+
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	expanded := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		expanded[i] = expandSlogAttr(a, &h2.first)
+	}
+	h2.goas = append(h.goas[:len(h.goas):len(h.goas)], slogGroupOrAttrs{attrs: expanded})
+	return &h2
+}
+
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.goas = append(h.goas[:len(h.goas):len(h.goas)], slogGroupOrAttrs{group: name})
+	return &h2
+}
+
+/*
+	Return the attr with any errcat error in it expanded (recursing into
+	groups), noting the first errcat error seen in *first, if it's not
+	already set.
+*/
+func expandSlogAttr(a slog.Attr, first *Error) slog.Attr {
+	switch a.Value.Kind() {
+	case slog.KindAny, slog.KindLogValuer:
+		err, ok := a.Value.Any().(error)
+		if !ok {
+			return a
+		}
+		if err = denil(err, "SlogHandler"); err == nil {
+			return slog.Any(a.Key, nil)
+		}
+		e := firstErrcat(err)
+		if e == nil {
+			return a
+		}
+		if *first == nil {
+			*first = e
+		}
+		return slog.Attr{Key: a.Key, Value: errorValue(err)}
+	case slog.KindGroup:
+		group := a.Value.Group()
+		expanded := make([]slog.Attr, len(group))
+		for i, ga := range group {
+			expanded[i] = expandSlogAttr(ga, first)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(expanded...)}
+	default:
+		return a
+	}
+}
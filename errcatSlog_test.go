@@ -0,0 +1,78 @@
+package errcat_test
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/warpfork/go-errcat"
+)
+
+func newTestSlogger(buf *bytes.Buffer, wrap func(slog.Handler) slog.Handler) *slog.Logger {
+	h := slog.Handler(slog.NewTextHandler(buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	if wrap != nil {
+		h = wrap(h)
+	}
+	return slog.New(h)
+}
+
+func TestSlog(t *testing.T) {
+	when := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	err := errcat.New(ErrAsdf, "disk full",
+		errcat.WithDetailPairs("path", "/tmp/foo", "attempt", "2"),
+		errcat.WithCause(errcat.New(ErrorCategoryA("err-a"), "no space", errcat.WithTime(when))),
+	)
+	t.Run("errors are log valuers", func(t *testing.T) {
+		var buf bytes.Buffer
+		newTestSlogger(&buf, nil).Error("failed", "err", err)
+		expect := `level=ERROR msg=failed err.category=err-asdf err.message="disk full" err.details.attempt=2 err.details.path=/tmp/foo err.cause.category=err-a err.cause.message="no space" err.cause.time=2026-01-02T03:04:05.000Z` + "\n"
+		if buf.String() != expect {
+			t.Errorf("must match golden --\ngot:  %s\nwant: %s", buf.String(), expect)
+		}
+	})
+	t.Run("the handler expands wrapped errors and adds the category", func(t *testing.T) {
+		var buf bytes.Buffer
+		log := newTestSlogger(&buf, func(h slog.Handler) slog.Handler {
+			return errcat.NewSlogHandler(h, &errcat.SlogHandlerOptions{CategoryKey: "error_category"})
+		})
+		log.WithGroup("req").Info("failed", "err", fmt.Errorf("handling: %w", errcat.Errorf(ErrAsdf, "nope")), "n", 1)
+		expect := `level=INFO msg=failed req.err.message="handling: nope" req.err.cause.category=err-asdf req.err.cause.message=nope req.n=1 error_category=err-asdf` + "\n"
+		if buf.String() != expect {
+			t.Errorf("must match golden --\ngot:  %s\nwant: %s", buf.String(), expect)
+		}
+	})
+	t.Run("the handler expands errors in groups and in WithAttrs", func(t *testing.T) {
+		var buf bytes.Buffer
+		log := newTestSlogger(&buf, func(h slog.Handler) slog.Handler {
+			return errcat.NewSlogHandler(h, &errcat.SlogHandlerOptions{CategoryKey: "error_category"})
+		})
+		log = log.With("outer", otherCopyRejection()).WithGroup("g")
+		log.Info("hi", slog.Group("sub", "err", errcat.Errorf(ErrAsdf, "x")))
+		log.Info("bye")
+		expect := `level=INFO msg=hi outer.category=errcat-category-filter-rejection outer.message="rejected over there" g.sub.err.category=err-asdf g.sub.err.message=x error_category=err-asdf` + "\n" +
+			`level=INFO msg=bye outer.category=errcat-category-filter-rejection outer.message="rejected over there" error_category=errcat-category-filter-rejection` + "\n"
+		if buf.String() != expect {
+			t.Errorf("must match golden --\ngot:\n%s\nwant:\n%s", buf.String(), expect)
+		}
+	})
+	t.Run("the handler leaves other things alone", func(t *testing.T) {
+		var buf bytes.Buffer
+		log := newTestSlogger(&buf, func(h slog.Handler) slog.Handler {
+			return errcat.NewSlogHandler(h, &errcat.SlogHandlerOptions{CategoryKey: "error_category"})
+		})
+		log.Warn("meh", "err", fmt.Errorf("plain"), "k", "v")
+		expect := `level=WARN msg=meh err=plain k=v` + "\n"
+		if buf.String() != expect {
+			t.Errorf("must match golden --\ngot:  %s\nwant: %s", buf.String(), expect)
+		}
+	})
+}
@@ -0,0 +1,172 @@
+package errcat
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+/*
+	Encoder writes errors as newline-delimited json: one errcat json object
+	per line.
+
+	This is handy for shipping errors in bulk (batch job results, dead-letter
+	queues, and so on); read them back with a `Decoder`.
+*/
+type Encoder struct {
+	w io.Writer
+}
+
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w}
+}
+
+/*
+	Write one error as a line of json.
+
+	Non-errcat errors are written with the `unknown` category and their
+	`Error()` text.  Nil errors are skipped; nothing is written.
+*/
+func (enc *Encoder) Encode(err error) error {
+	if err = denil(err, "Encoder.Encode"); err == nil {
+		return nil
+	}
+	bs, err := json.Marshal(toJSONErr(err))
+	if err != nil {
+		return err
+	}
+	_, err = enc.w.Write(append(bs, '\n'))
+	return err
+}
+
+/*
+	Decoder reads errors from newline-delimited json, as written by an `Encoder`.
+
+	Memory use is bounded: lines are read one at a time, and any line longer
+	than the limit (see `SetMaxLineBytes`) is skipped and reported rather
+	than buffered.
+*/
+type Decoder struct {
+	r        *bufio.Reader
+	buf      []byte
+	line     int
+	maxLine  int
+	strict   bool
+	finished bool
+}
+
+// DefaultMaxLineBytes is the longest line a Decoder accepts, unless told otherwise.
+const DefaultMaxLineBytes = 1 << 20
+
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r), maxLine: DefaultMaxLineBytes}
+}
+
+// Set the longest line (not counting the newline) the decoder will accept.
+func (dec *Decoder) SetMaxLineBytes(n int) {
+	dec.maxLine = n
+}
+
+// Decode each line with `ParseJSONStrict` instead of `ParseJSON`.
+func (dec *Decoder) SetStrict(strict bool) {
+	dec.strict = strict
+}
+
+// Return the line number of the line most recently read (counting from 1).
+func (dec *Decoder) Line() int {
+	return dec.line
+}
+
+/*
+	Read the next error.
+
+	Returns `io.EOF` when the input is exhausted.
+	Blank lines are skipped.
+
+	Lines which can't be decoded return an error of category `ErrDecoding`,
+	with the line number in its message and in a "line" detail;
+	decoding can continue with the next line afterwards.
+	Categories are restored to their typed values if they have been
+	registered with `RegisterCategory`.
+*/
+func (dec *Decoder) Decode() (Error, error) {
+	for {
+		line, tooLong, err := dec.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if tooLong {
+			return nil, dec.lineError(Errorf(ErrDecoding, "line exceeds %d bytes", dec.maxLine))
+		}
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		e, err := parseJSON(line, dec.strict)
+		if err != nil {
+			return nil, dec.lineError(err)
+		}
+		return e, nil
+	}
+}
+
+func (dec *Decoder) lineError(err error) error {
+	return ErrorDetailed(ErrDecoding,
+		fmt.Sprintf("errcat: ndjson line %d: %s", dec.line, err.Error()),
+		mergeDetails(Details(err), "line", strconv.Itoa(dec.line)),
+	)
+}
+
+/*
+	Read one line, without its newline.
+	If the line is longer than the limit, its content is discarded and
+	tooLong is set.
+*/
+func (dec *Decoder) readLine() (line []byte, tooLong bool, err error) {
+	if dec.finished {
+		return nil, false, io.EOF
+	}
+	dec.buf = dec.buf[:0]
+	sawAny := false
+	for {
+		chunk, err := dec.r.ReadSlice('\n')
+		sawAny = sawAny || len(chunk) > 0
+		if !tooLong {
+			if len(dec.buf)+len(bytes.TrimSuffix(chunk, []byte{'\n'})) > dec.maxLine {
+				tooLong = true
+				dec.buf = dec.buf[:0]
+			} else {
+				dec.buf = append(dec.buf, chunk...)
+			}
+		}
+		switch err {
+		case nil:
+			dec.line++
+			return bytes.TrimSuffix(dec.buf, []byte{'\n'}), tooLong, nil
+		case bufio.ErrBufferFull:
+			continue
+		case io.EOF:
+			dec.finished = true
+			if !sawAny {
+				return nil, false, io.EOF
+			}
+			dec.line++
+			return dec.buf, tooLong, nil
+		default:
+			return nil, false, err
+		}
+	}
+}
+
+// Copy a details map, adding one more entry.
+func mergeDetails(details map[string]string, key string, value string) map[string]string {
+	d2 := make(map[string]string, len(details)+1)
+	for k, v := range details {
+		d2[k] = v
+	}
+	d2[key] = value
+	return d2
+}
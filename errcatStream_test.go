@@ -0,0 +1,86 @@
+package errcat_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/warpfork/go-errcat"
+)
+
+func TestStream(t *testing.T) {
+	t.Run("encoding must match fixture", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := errcat.NewEncoder(&buf)
+		for _, err := range []error{
+			errcat.Errorf(ErrAsdf, "one"),
+			nil,
+			errcat.ErrorDetailed(ErrXMLRegistered, "two", map[string]string{"k": "v"}),
+			fmt.Errorf("three"),
+		} {
+			if err := enc.Encode(err); err != nil {
+				t.Fatal(err)
+			}
+		}
+		expect := `{"category":"err-asdf","message":"one"}` + "\n" +
+			`{"category":"err-xml","message":"two","details":{"k":"v"}}` + "\n" +
+			`{"category":"unknown-category","message":"three"}` + "\n"
+		if buf.String() != expect {
+			t.Errorf("must match fixture -- got `%s`", buf.String())
+		}
+	})
+	t.Run("decoding reports each bad line and continues", func(t *testing.T) {
+		dec := errcat.NewDecoder(strings.NewReader(`{"category":"err-xml","message":"one"}` + "\n" +
+			"\n" +
+			`{"category":` + "\n" +
+			`{"category":"err-xml","message":"two"}`,
+		))
+		e, err := dec.Decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		shouldCategory(t, e, ErrXMLRegistered)
+		_, err = dec.Decode()
+		shouldCategory(t, err, errcat.ErrDecoding)
+		if errcat.Details(err)["line"] != "3" || !strings.HasPrefix(err.Error(), "errcat: ndjson line 3: ") {
+			t.Errorf("must report line 3, got %q %v", err, errcat.Details(err))
+		}
+		e, err = dec.Decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Message() != "two" || dec.Line() != 4 {
+			t.Errorf("must continue to line 4, got %q at line %d", e.Message(), dec.Line())
+		}
+		if _, err = dec.Decode(); err != io.EOF {
+			t.Errorf("must end with EOF, got %v", err)
+		}
+	})
+	t.Run("decoding skips over-long lines without buffering them", func(t *testing.T) {
+		long := `{"category":"err-xml","message":"` + strings.Repeat("x", 10000) + `"}`
+		dec := errcat.NewDecoder(strings.NewReader(long + "\n" + `{"category":"err-xml","message":"short"}` + "\n"))
+		dec.SetMaxLineBytes(100)
+		_, err := dec.Decode()
+		shouldCategory(t, err, errcat.ErrDecoding)
+		if errcat.Details(err)["line"] != "1" {
+			t.Errorf("must report line 1, got %v", err)
+		}
+		e, err := dec.Decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Message() != "short" {
+			t.Errorf("must continue, got %q", e.Message())
+		}
+	})
+	t.Run("strict decoding", func(t *testing.T) {
+		dec := errcat.NewDecoder(strings.NewReader(`{"category":"err-xml","msg":"one"}` + "\n"))
+		dec.SetStrict(true)
+		_, err := dec.Decode()
+		if errcat.Details(err)["rule"] != "legacy-msg-key" || errcat.Details(err)["line"] != "1" {
+			t.Errorf("must report rule and line, got %v", errcat.Details(err))
+		}
+	})
+}
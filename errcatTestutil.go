@@ -21,6 +21,9 @@ func ErrorShouldHaveCategory(actual interface{}, expectedClause ...interface{})
 		return "Misuse: ShouldErrorWithCategory predicate needs exactly one item in the \"expected\" clause"
 	}
 	expected := expectedClause[0]
+	if err, ok := actual.(error); ok && denil(err, "ErrorShouldHaveCategory") == nil {
+		actual = nil
+	}
 	if actual == nil && expected == nil {
 		return "" // good!
 	}
@@ -35,7 +38,7 @@ func ErrorShouldHaveCategory(actual interface{}, expectedClause ...interface{})
 	if !ok {
 		return fmt.Sprintf("Actual: %v\nExpected category: %q\nShould have an errcat error!  Was type %T.", actual, expected, actual)
 	}
-	if e2.Category() != expected {
+	if Category(e2) != expected {
 		return fmt.Sprintf("Actual category: %q\nExpected category: %q\n(Full error: %v)", e2.Category(), expected, actual)
 	}
 	return "" // couldn't find grounds to reject it; must be good!
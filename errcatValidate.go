@@ -0,0 +1,105 @@
+package errcat
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+/*
+	Categories must be comparable (or `switch errcat.Category(err)` panics)
+	and serializable as a string (or every encoder writes garbage).
+	Accepted are: values of any string kind, and comparable values
+	implementing `fmt.Stringer` or `encoding.TextMarshaler`.
+	Nil is not a category.
+
+	The factories check this as errors are constructed, rather than letting
+	a bad category blow up far away in some switch or encoder.
+	A bad category is replaced with `ErrInvalidCategory` (with a message
+	saying what was wrong), or -- if you've opted in with
+	`SetStrictCategories` -- it's a panic, on the spot.
+
+	The verdict is cached per type, so this costs next to nothing:
+	plain strings (and our own categories) don't even get that far.
+*/
+func SetStrictCategories(strict bool) {
+	var v int32
+	if strict {
+		v = 1
+	}
+	atomic.StoreInt32(&strictCategories, v)
+}
+
+var strictCategories int32
+
+var categoryVerdicts sync.Map // map[reflect.Type]string; the problem with categories of that type, or empty if none.
+
+var (
+	rtStringer      = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+	rtTextMarshaler = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+/*
+	Return a new errStruct, if the category is acceptable.
+	If not, the returned error has `ErrInvalidCategory` instead
+	(or we panic, in strict mode).
+*/
+func newErrStruct(category interface{}, msg string, details map[string]string) *errStruct {
+	b := NewErrorBase(category, msg, details)
+	return &errStruct{Category_: b.Category_, Message_: b.Message_, Details_: b.Details_}
+}
+
+/*
+	Return a base (for embedding in your own error types; see `ErrorBase`)
+	with the given category, message, and details map.
+	The category is checked exactly as the factories check it.
+*/
+func NewErrorBase(category interface{}, msg string, details map[string]string) ErrorBase {
+	category, msg = checkCategory(category, msg)
+	return ErrorBase{category, msg, details}
+}
+
+/*
+	Return the category and message unchanged if the category is acceptable;
+	or `ErrInvalidCategory` and a message saying what was wrong, if not
+	(or panic, in strict mode).
+*/
+func checkCategory(category interface{}, msg string) (interface{}, string) {
+	problem := categoryProblem(category)
+	if problem == "" {
+		return category, msg
+	}
+	if atomic.LoadInt32(&strictCategories) != 0 {
+		panic(fmt.Sprintf("errcat: invalid category %#v: %s", category, problem))
+	}
+	return ErrInvalidCategory, fmt.Sprintf("errcat: invalid category of type %T: %s (original error: %s)", category, problem, msg)
+}
+
+// Return what's wrong with a category, or empty if it's fine.
+func categoryProblem(category interface{}) string {
+	switch category.(type) {
+	case string, errorCategory:
+		return ""
+	case nil:
+		return "category must not be nil"
+	}
+	rt := reflect.TypeOf(category)
+	if v, ok := categoryVerdicts.Load(rt); ok {
+		return v.(string)
+	}
+	var problem string
+	switch {
+	case !rt.Comparable():
+		problem = "category must be comparable"
+	case rt.Kind() == reflect.String, rt.Implements(rtStringer), rt.Implements(rtTextMarshaler):
+		problem = ""
+	default:
+		problem = "category must be a string, fmt.Stringer, or encoding.TextMarshaler"
+	}
+	categoryVerdicts.Store(rt, problem)
+	return problem
+}
+
+const ErrInvalidCategory = errorCategory("errcat-invalid-category")
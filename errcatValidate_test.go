@@ -0,0 +1,73 @@
+package errcat_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/warpfork/go-errcat"
+)
+
+type StringerTestCategory struct{ code int }
+
+func (c StringerTestCategory) String() string { return "stringer" }
+
+type UncomparableTestCategory struct{ codes []int }
+
+func (c UncomparableTestCategory) String() string { return "uncomparable" }
+
+func TestCategoryValidation(t *testing.T) {
+	t.Run("acceptable categories", func(t *testing.T) {
+		for _, cat := range []interface{}{
+			"plain",
+			ErrAsdf,
+			StringerTestCategory{1},
+			&StringerTestCategory{2},
+			errcat.GRPCCodeNotFound,
+			errcat.SeverityWarning,
+		} {
+			shouldCategory(t, errcat.Errorf(cat, "ok"), cat)
+		}
+	})
+	t.Run("unacceptable categories", func(t *testing.T) {
+		for _, tr := range []struct {
+			category interface{}
+			problem  string
+		}{
+			{[]string{"x"}, "must be comparable"},
+			{map[string]string{}, "must be comparable"},
+			{UncomparableTestCategory{}, "must be comparable"},
+			{42, "must be a string"},
+			{nil, "must not be nil"},
+		} {
+			for _, err := range []error{
+				errcat.Errorf(tr.category, "orig"),
+				errcat.ErrorDetailed(tr.category, "orig", map[string]string{"k": "v"}),
+				errcat.Recategorize(tr.category, errcat.ErrorDetailed(ErrAsdf, "orig", map[string]string{"k": "v"})),
+			} {
+				shouldCategory(t, err, errcat.ErrInvalidCategory)
+				if !strings.Contains(err.Error(), tr.problem) || !strings.HasSuffix(err.Error(), "(original error: orig)") {
+					t.Errorf("message must explain -- got %q", err.Error())
+				}
+				switch errcat.Category(err) { // must not panic.
+				case ErrAsdf:
+				}
+			}
+		}
+	})
+	t.Run("strict mode panics", func(t *testing.T) {
+		errcat.SetStrictCategories(true)
+		defer errcat.SetStrictCategories(false)
+		defer func() {
+			if r := recover(); r == nil || !strings.Contains(r.(string), "must be comparable") {
+				t.Errorf("must panic -- got %v", r)
+			}
+		}()
+		errcat.Errorf([]string{"x"}, "orig")
+	})
+}
+
+func BenchmarkErrorfStringCategory(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		errcat.Errorf(ErrAsdf, "asdf")
+	}
+}
@@ -0,0 +1,64 @@
+package errcat_test
+
+import (
+	"testing"
+
+	"github.com/warpfork/go-errcat"
+)
+
+/*
+	Simulate a second copy of errcat, vendored under another import path,
+	in the same binary: its sentinel categories have a distinct type (which
+	is unexported there, just as ours is here), and its errors are its own
+	concrete type.
+*/
+type otherCopyCategory string
+
+func (c otherCopyCategory) ErrcatSentinel() string { return string(c) }
+
+type otherCopyError struct {
+	category otherCopyCategory
+	msg      string
+}
+
+func (e *otherCopyError) Category() interface{}      { return e.category }
+func (e *otherCopyError) Message() string            { return e.msg }
+func (e *otherCopyError) Details() map[string]string { return nil }
+func (e *otherCopyError) Error() string              { return e.msg }
+
+func otherCopyRejection() error {
+	return &otherCopyError{otherCopyCategory("errcat-category-filter-rejection"), "rejected over there"}
+}
+
+func TestVendoredSentinels(t *testing.T) {
+	t.Run("switching recognizes other copies' sentinels", func(t *testing.T) {
+		switch errcat.Category(otherCopyRejection()) {
+		case errcat.ErrCategoryFilterRejection:
+			// pass
+		default:
+			t.Errorf("must switch")
+		}
+	})
+	t.Run("filters do not re-reject other copies' rejections", func(t *testing.T) {
+		err := func() (err error) {
+			defer errcat.RequireErrorHasCategory(&err, ErrorCategoryA(""))
+			return otherCopyRejection()
+		}()
+		shouldCategory(t, err, errcat.ErrCategoryFilterRejection)
+		if err.Error() != "rejected over there" {
+			t.Errorf("must pass the original rejection through -- got %q", err.Error())
+		}
+	})
+	t.Run("other mappings recognize other copies' sentinels", func(t *testing.T) {
+		if code := errcat.GRPCCodeOf(otherCopyRejection()); code != errcat.GRPCCodeInternal {
+			t.Errorf("expected INTERNAL, got %s", code)
+		}
+		if msg := errcat.ErrorShouldHaveCategory(otherCopyRejection(), errcat.ErrCategoryFilterRejection); msg != "" {
+			t.Errorf("assertion must pass -- got %s", msg)
+		}
+	})
+	t.Run("unfamiliar sentinels are left alone", func(t *testing.T) {
+		err := &otherCopyError{otherCopyCategory("errcat-from-the-future"), "?"}
+		shouldCategory(t, err, otherCopyCategory("errcat-from-the-future"))
+	})
+}
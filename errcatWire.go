@@ -0,0 +1,79 @@
+package errcat
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+/*
+	WireVersion is the newest version of the json wire format which this
+	package understands (and emits).
+
+	Serialized errors may carry their version in a "v" field.
+	The marker is optional: an object without one is version 1, and we only
+	write the marker when an error needs a version newer than that, so
+	errors which don't use newer features keep serializing exactly as they
+	always have.
+
+	Version history:
+
+		1 -- {"category", "message", "details"}.  Never carries a marker.
+		2 -- adds "cause" (a nested error object, without a marker of its own)
+		     and "time" (RFC 3339, with fractional seconds if any); see `New`.
+		     Errors with neither still serialize as version 1.
+
+	Decoders understand every version up to this one: older objects are
+	brought up to date by the migrations in `wireMigrations` before being
+	interpreted.  Objects claiming a newer version than this are rejected.
+*/
+const WireVersion = 2
+
+/*
+	wireMigrations holds the function which upgrades a decoded json object
+	from version N to version N+1, keyed by N.
+	Migrations edit the object in place.
+
+	Every version bump must add an entry here (even if it's a no-op because
+	the new version only added optional fields), and add fixtures for the
+	new version to the compatibility tests.
+*/
+var wireMigrations = map[int]func(obj map[string]interface{}) error{
+	1: func(obj map[string]interface{}) error { return nil }, // version 2 only added optional fields.
+}
+
+/*
+	Bring a decoded json object up to `WireVersion`, removing the version
+	marker as we go.
+*/
+func migrateJSONObject(obj map[string]interface{}) error {
+	version := 1
+	if v, ok := obj["v"]; ok {
+		n, ok := v.(json.Number)
+		i, err := strconv.Atoi(string(n))
+		if !ok || err != nil || i < 1 {
+			return jsonRuleError("invalid-version", "v", "errcat: invalid json: version must be a positive integer")
+		}
+		version = i
+		delete(obj, "v")
+	}
+	if version > WireVersion {
+		return jsonRuleError("unsupported-version", "v", "errcat: invalid json: version %d is newer than this decoder understands (%d)", version, WireVersion)
+	}
+	for ; version < WireVersion; version++ {
+		if err := wireMigrations[version](obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+	Return the oldest wire version which can represent the error.
+	This is what goes in the "v" marker (which is omitted for version 1).
+*/
+func wireVersionOf(e *errStruct) int {
+	if e.cause() != nil || !e.when().IsZero() {
+		return 2
+	}
+	return 1
+}
@@ -0,0 +1,105 @@
+package errcat_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/warpfork/go-errcat"
+)
+
+// wireFixtures pins the json form of errors as emitted by each version of the wire format.
+// Never edit or remove these; add more when the format changes.
+var wireFixtures = []struct {
+	version int
+	title   string
+	json    string
+	err     error // what the fixture must decode to, and (for the current version) what must encode to it.
+}{
+	{1, "plain",
+		`{"category":"err-xml","message":"a msg"}`,
+		errcat.Errorf(ErrXMLRegistered, "a msg")},
+	{1, "with details",
+		`{"category":"err-xml","message":"a msg","details":{"a":"b","k":"v"}}`,
+		errcat.ErrorDetailed(ErrXMLRegistered, "a msg", map[string]string{"k": "v", "a": "b"})},
+	{1, "with explicit marker",
+		`{"v":1,"category":"err-xml","message":"a msg"}`,
+		errcat.Errorf(ErrXMLRegistered, "a msg")},
+	{2, "with cause",
+		`{"v":2,"category":"err-xml","message":"a msg","cause":{"category":"err-xml","message":"inner","details":{"k":"v"}}}`,
+		errcat.New(ErrXMLRegistered, "a msg", errcat.WithCause(errcat.ErrorDetailed(ErrXMLRegistered, "inner", map[string]string{"k": "v"})))},
+	{2, "with non-errcat cause",
+		`{"v":2,"category":"err-xml","message":"a msg","cause":{"category":"unknown-category","message":"EOF"}}`,
+		errcat.New(ErrXMLRegistered, "a msg", errcat.WithCause(io.EOF))},
+	{2, "with time",
+		`{"v":2,"category":"err-xml","message":"a msg","time":"2026-01-02T03:04:05.6Z"}`,
+		errcat.New(ErrXMLRegistered, "a msg", errcat.WithTime(time.Date(2026, 1, 2, 3, 4, 5, 6e8, time.UTC)))},
+	{2, "with nested causes and time",
+		`{"v":2,"category":"err-xml","message":"a msg","cause":{"category":"err-xml","message":"middle","cause":{"category":"err-xml","message":"inner"},"time":"2026-01-02T03:04:05Z"}}`,
+		errcat.New(ErrXMLRegistered, "a msg", errcat.WithCause(errcat.New(ErrXMLRegistered, "middle",
+			errcat.WithCause(errcat.Errorf(ErrXMLRegistered, "inner")),
+			errcat.WithTime(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)))))},
+}
+
+func TestWireCompatibility(t *testing.T) {
+	for _, fix := range wireFixtures {
+		t.Run(fix.title, func(t *testing.T) {
+			t.Run("must decode", func(t *testing.T) {
+				e2, err := errcat.ParseJSONStrict([]byte(fix.json))
+				if err != nil {
+					t.Fatal(err)
+				}
+				shouldCategory(t, e2, errcat.Category(fix.err))
+				if e2.Message() != fix.err.Error() {
+					t.Errorf("expected message %q, got %q", fix.err.Error(), e2.Message())
+				}
+				if !reflect.DeepEqual(e2.Details(), errcat.Details(fix.err)) {
+					t.Errorf("expected details %v, got %v", errcat.Details(fix.err), e2.Details())
+				}
+				for want, got := fix.err, error(e2); want != nil || got != nil; want, got = errors.Unwrap(want), errors.Unwrap(got) {
+					if want == nil || got == nil || want.Error() != got.Error() || !errcat.Timestamp(want).Equal(errcat.Timestamp(got)) {
+						t.Errorf("cause chain mismatch: expected %v, got %v", want, got)
+						break
+					}
+				}
+			})
+			// Errors are written at the oldest version which can represent them,
+			// so fixtures of every version must still encode exactly...
+			var unmarked map[string]interface{}
+			json.Unmarshal([]byte(fix.json), &unmarked)
+			if _, marked := unmarked["v"]; marked && fix.version == 1 {
+				return // ... except that we never emit the marker for version 1.
+			}
+			t.Run("must encode", func(t *testing.T) {
+				bytes, err := json.Marshal(fix.err)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(bytes) != fix.json {
+					t.Errorf("must match fixture -- got `%s`", string(bytes))
+				}
+			})
+		})
+	}
+	t.Run("versions must be understood", func(t *testing.T) {
+		for _, tr := range []struct {
+			json string
+			rule string
+		}{
+			{`{"v":99,"category":"err-xml","message":"a msg"}`, "unsupported-version"},
+			{`{"v":2,"category":"err-xml","message":"a msg","cause":"nope"}`, "cause-not-object"},
+			{`{"v":0,"category":"err-xml","message":"a msg"}`, "invalid-version"},
+			{`{"v":"1","category":"err-xml","message":"a msg"}`, "invalid-version"},
+			{`{"v":1.5,"category":"err-xml","message":"a msg"}`, "invalid-version"},
+		} {
+			_, err := errcat.ParseJSON([]byte(tr.json))
+			shouldCategory(t, err, errcat.ErrDecoding)
+			if rule := errcat.Details(err)["rule"]; rule != tr.rule {
+				t.Errorf("%s: expected rule %q, got %q", tr.json, tr.rule, rule)
+			}
+		}
+	})
+}
@@ -0,0 +1,128 @@
+package errcat
+
+import (
+	"encoding/xml"
+	"fmt"
+	"time"
+)
+
+/*
+	MarshalXML renders the error as an element mirroring the json form:
+
+		<error>
+			<category>your_tag</category>
+			<message>full text goes here</message>
+			<detail key="foo">bar</detail>
+			<time>2006-01-02T15:04:05Z</time>
+			<cause>
+				<category>...</category>
+				<message>...</message>
+			</cause>
+		</error>
+
+	The time and cause are only there if the error has them (see `WithTime`
+	and `WithCause`); non-errcat causes are written as if they had the
+	`unknown` category, as in json.
+	Details are written in sorted key order, so output is deterministic.
+	The element is named "error" unless the caller names it (e.g. with a
+	struct field tag).
+*/
+func (e *errStruct) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
+	if start.Name.Local == "" || start.Name.Local == "errStruct" {
+		start.Name = xml.Name{Local: "error"}
+	}
+	return enc.EncodeElement(makeXMLErr(e), start)
+}
+
+/*
+	UnmarshalXML restores the category (and those of its causes) to its
+	typed value if it has been registered with `RegisterCategory`;
+	otherwise it's left as a plain string.
+*/
+func (e *errStruct) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
+	var x xmlErr
+	if err := dec.DecodeElement(&x, &start); err != nil {
+		return err
+	}
+	e2, err := x.decode()
+	if err != nil {
+		return err
+	}
+	*e = *e2
+	return nil
+}
+
+/*
+	Parse an errcat error from its xml form (as produced by marshalling any
+	errcat error with `encoding/xml`).
+
+	Malformed input returns an error of category `ErrDecoding`.
+*/
+func ParseXML(data []byte) (Error, error) {
+	var e errStruct
+	if err := xml.Unmarshal(data, &e); err != nil {
+		return nil, Errorf(ErrDecoding, "errcat: malformed xml: %s", err)
+	}
+	return &e, nil
+}
+
+type xmlErr struct {
+	Category string      `xml:"category"`
+	Message  string      `xml:"message"`
+	Details  []xmlDetail `xml:"detail"`
+	Time     string      `xml:"time,omitempty"`
+	Cause    *xmlErr     `xml:"cause,omitempty"`
+}
+
+type xmlDetail struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+func makeXMLErr(err error) xmlErr {
+	e, ok := err.(Error)
+	if !ok {
+		return xmlErr{Category: categoryString(unknown), Message: err.Error()}
+	}
+	x := xmlErr{Category: categoryString(e.Category()), Message: e.Message()}
+	details := e.Details()
+	for _, k := range sortedDetailKeys(details) {
+		x.Details = append(x.Details, xmlDetail{k, details[k]})
+	}
+	if e2, ok := e.(*errStruct); ok {
+		if !e2.when().IsZero() {
+			x.Time = e2.when().Format(time.RFC3339Nano)
+		}
+		if e2.cause() != nil {
+			cause := makeXMLErr(e2.cause())
+			x.Cause = &cause
+		}
+	}
+	return x
+}
+
+func (x xmlErr) decode() (*errStruct, error) {
+	var details map[string]string
+	if len(x.Details) > 0 {
+		details = make(map[string]string, len(x.Details))
+		for _, d := range x.Details {
+			details[d.Key] = d.Value
+		}
+	}
+	e := decodedError(x.Category, x.Message, details)
+	if x.Time != "" {
+		t, err := time.Parse(time.RFC3339Nano, x.Time)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time %q", x.Time)
+		}
+		e.ext().Time_ = t
+	}
+	if x.Cause != nil {
+		cause, err := x.Cause.decode()
+		if err != nil {
+			return nil, err
+		}
+		e.ext().Cause_ = cause
+	}
+	return e, nil
+}
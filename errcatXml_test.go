@@ -0,0 +1,115 @@
+package errcat_test
+
+import (
+	"encoding/xml"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/warpfork/go-errcat"
+)
+
+type XMLTestCategory string
+
+const (
+	ErrXMLRegistered = XMLTestCategory("err-xml")
+)
+
+func init() {
+	errcat.RegisterCategory(ErrXMLRegistered)
+}
+
+func TestXMLSerialization(t *testing.T) {
+	e1 := errcat.ErrorDetailed(ErrXMLRegistered, "a <msg>", map[string]string{"zed": "1", "alpha": "2 & 3"})
+	bytes, err := xml.Marshal(e1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Run("must match fixture", func(t *testing.T) {
+		expect := `<error><category>err-xml</category><message>a &lt;msg&gt;</message><detail key="alpha">2 &amp; 3</detail><detail key="zed">1</detail></error>`
+		if string(bytes) != expect {
+			t.Errorf("must match fixture -- got `%s`", string(bytes))
+		}
+	})
+	t.Run("must match fixture without details", func(t *testing.T) {
+		bytes, err := xml.Marshal(errcat.Errorf(ErrAsdf, "asdf: %s", "fmtme"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(bytes) != `<error><category>err-asdf</category><message>asdf: fmtme</message></error>` {
+			t.Errorf("must match fixture -- got `%s`", string(bytes))
+		}
+	})
+	t.Run("must roundtrip", func(t *testing.T) {
+		e2, err := errcat.ParseXML(bytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		shouldCategory(t, e2, ErrXMLRegistered)
+		if e2.Message() != "a <msg>" {
+			t.Errorf("message must roundtrip -- got %q", e2.Message())
+		}
+		if len(e2.Details()) != 2 || e2.Details()["alpha"] != "2 & 3" {
+			t.Errorf("details must roundtrip -- got %v", e2.Details())
+		}
+	})
+	t.Run("causes and times roundtrip", func(t *testing.T) {
+		when := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+		bytes, err := xml.Marshal(errcat.New(ErrXMLRegistered, "outer", errcat.WithCause(errors.New("inner")), errcat.WithTime(when)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect := `<error><category>err-xml</category><message>outer</message><time>2026-01-02T03:04:05Z</time><cause><category>unknown-category</category><message>inner</message></cause></error>`
+		if string(bytes) != expect {
+			t.Errorf("must match fixture -- got `%s`", string(bytes))
+		}
+		e2, err := errcat.ParseXML(bytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !errcat.Timestamp(e2).Equal(when) {
+			t.Errorf("time must roundtrip -- got %v", errcat.Timestamp(e2))
+		}
+		cause := errors.Unwrap(e2)
+		shouldCategory(t, cause, errcat.Category(errors.New("wild")))
+		if cause == nil || cause.Error() != "inner" {
+			t.Errorf("cause must roundtrip -- got %v", cause)
+		}
+	})
+	t.Run("invalid times are rejected", func(t *testing.T) {
+		_, err := errcat.ParseXML([]byte(`<error><category>err-xml</category><message>hi</message><time>yesterday</time></error>`))
+		shouldCategory(t, err, errcat.ErrDecoding)
+	})
+	t.Run("unregistered categories decode as strings", func(t *testing.T) {
+		e2, err := errcat.ParseXML([]byte(`<error><category>err-who</category><message>hi</message></error>`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		shouldCategory(t, e2, "err-who")
+	})
+	t.Run("embedded in structs", func(t *testing.T) {
+		type envelope struct {
+			XMLName xml.Name     `xml:"response"`
+			Fault   errcat.Error `xml:"fault"`
+		}
+		bytes, err := xml.Marshal(envelope{Fault: e1.(errcat.Error)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect := `<response><fault><category>err-xml</category><message>a &lt;msg&gt;</message><detail key="alpha">2 &amp; 3</detail><detail key="zed">1</detail></fault></response>`
+		if string(bytes) != expect {
+			t.Errorf("must match fixture -- got `%s`", string(bytes))
+		}
+	})
+	t.Run("missing categories decode as missing", func(t *testing.T) {
+		e2, err := errcat.ParseXML([]byte(`<error><message>hi</message></error>`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		shouldCategory(t, e2, errcat.ErrMissingCategory)
+	})
+	t.Run("malformed input is rejected", func(t *testing.T) {
+		_, err := errcat.ParseXML([]byte(`<error><category>`))
+		shouldCategory(t, err, errcat.ErrDecoding)
+	})
+}